@@ -0,0 +1,253 @@
+package resty
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// Implemented by both schema.ResourceData and schema.ResourceDiff
+type resourceGetter interface {
+	Get(key string) interface{}
+}
+
+// Body attributes shared by the resource and the data sources
+func bodySchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"body": {
+			Type:          schema.TypeString,
+			Description:   "JSON object sent as request body, usually built with jsonencode. Unlike data it is shown in plans",
+			Optional:      true,
+			ConflictsWith: []string{"data"},
+			ValidateFunc:  validateJSONObject,
+			StateFunc:     normalizeBody,
+		},
+		"sensitive_body": {
+			Type:          schema.TypeString,
+			Description:   "JSON object deep merged into body, for the parts of it which are secret",
+			Optional:      true,
+			Sensitive:     true,
+			ConflictsWith: []string{"data"},
+			ValidateFunc:  validateJSONObject,
+			StateFunc:     normalizeBody,
+		},
+		"body_format": {
+			Type:         schema.TypeString,
+			Description:  "Encoding of the request body: json, form, multipart, raw or xml. Defaults to json",
+			Optional:     true,
+			ValidateFunc: validation.StringInSlice([]string{"json", "form", "multipart", "raw", "xml"}, false),
+		},
+		"form": {
+			Type:        schema.TypeMap,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+			Description: "Fields sent instead of data with body_format form or multipart",
+			Optional:    true,
+			Sensitive:   true,
+		},
+		"multipart": {
+			Type:        schema.TypeList,
+			Description: "Parts sent with body_format multipart",
+			Optional:    true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"name": {
+						Type:     schema.TypeString,
+						Required: true,
+					},
+					"value": {
+						Type:      schema.TypeString,
+						Optional:  true,
+						Sensitive: true,
+					},
+					"file": {
+						Type:        schema.TypeString,
+						Description: "Path of a file sent as content of the part",
+						Optional:    true,
+					},
+					"filename": {
+						Type:        schema.TypeString,
+						Description: "File name of the part. Defaults to the name of file",
+						Optional:    true,
+					},
+					"content_type": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+				},
+			},
+		},
+	}
+}
+
+// Return the raw request payload: data as is, or body with sensitive_body
+// merged into it
+func requestData(d resourceGetter) (string, error) {
+	body := d.Get("body").(string)
+	sensitive_body := d.Get("sensitive_body").(string)
+
+	if body == "" && sensitive_body == "" {
+		return d.Get("data").(string), nil
+	}
+
+	merged := make(map[string]interface{})
+	for _, v := range []string{body, sensitive_body} {
+		if v == "" {
+			continue
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(v), &object); err != nil {
+			return "", fmt.Errorf("Error parsing body: %s", err)
+		}
+		mergeObjects(merged, object)
+	}
+
+	out, err := json.Marshal(merged)
+	if err != nil {
+		return "", fmt.Errorf("Error encoding body: %s", err)
+	}
+	return string(out), nil
+}
+
+// Recursively copy src into dst, nested objects are merged rather than replaced
+func mergeObjects(dst map[string]interface{}, src map[string]interface{}) {
+	for k, v := range src {
+		child, ok := v.(map[string]interface{})
+		existing, exists := dst[k].(map[string]interface{})
+		if ok && exists {
+			mergeObjects(existing, child)
+		} else {
+			dst[k] = v
+		}
+	}
+}
+
+// Keep body canonical in the state, so reordering keys doesn't show a diff
+func normalizeBody(v interface{}) string {
+	return normalizeResponse([]byte(v.(string)))
+}
+
+func validateJSONObject(v interface{}, k string) (ws []string, es []error) {
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(v.(string)), &object); err != nil {
+		es = append(es, fmt.Errorf("%s must be a JSON object: %s", k, err))
+	}
+	return
+}
+
+// Encode the request payload according to body_format and return it along
+// with its Content-Type. The form and multipart fields replace data, unless
+// with_fields is false as it is for destroy requests.
+func requestBody(d *schema.ResourceData, data string, with_fields bool) (string, string, error) {
+	data, err := restyTemplate(d, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	form := make(map[string]interface{})
+	for k, v := range d.Get("form").(map[string]interface{}) {
+		if form[k], err = restyTemplate(d, v.(string)); err != nil {
+			return "", "", err
+		}
+	}
+
+	switch d.Get("body_format").(string) {
+	case "form":
+		if with_fields && len(form) > 0 {
+			values := url.Values{}
+			for k, v := range form {
+				values.Set(k, v.(string))
+			}
+			data = values.Encode()
+		}
+		return data, "application/x-www-form-urlencoded", nil
+	case "multipart":
+		if !with_fields {
+			return data, "", nil
+		}
+		return multipartBody(form, d.Get("multipart").([]interface{}))
+	case "xml":
+		return data, "application/xml", nil
+	case "raw":
+		return data, "", nil
+	}
+
+	return data, "application/json", nil
+}
+
+// Build a multipart/form-data body out of plain fields and file parts
+func multipartBody(form map[string]interface{}, parts []interface{}) (string, string, error) {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+
+	// keep the field order stable
+	keys := make([]string, 0, len(form))
+	for k := range form {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := writer.WriteField(k, form[k].(string)); err != nil {
+			return "", "", fmt.Errorf("Error writing multipart field %s: %s", k, err)
+		}
+	}
+
+	for _, p := range parts {
+		part := p.(map[string]interface{})
+		name := part["name"].(string)
+		file := part["file"].(string)
+		filename := part["filename"].(string)
+		content_type := part["content_type"].(string)
+		content := []byte(part["value"].(string))
+
+		if file != "" {
+			var err error
+			if content, err = ioutil.ReadFile(file); err != nil {
+				return "", "", fmt.Errorf("Error reading multipart file %s: %s", file, err)
+			}
+			if filename == "" {
+				filename = filepath.Base(file)
+			}
+			if content_type == "" {
+				content_type = "application/octet-stream"
+			}
+		}
+
+		disposition := fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(name))
+		if filename != "" {
+			disposition += fmt.Sprintf(`; filename="%s"`, quoteEscaper.Replace(filename))
+		}
+
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", disposition)
+		if content_type != "" {
+			header.Set("Content-Type", content_type)
+		}
+
+		w, err := writer.CreatePart(header)
+		if err == nil {
+			_, err = w.Write(content)
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("Error writing multipart part %s: %s", name, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", "", fmt.Errorf("Error finishing multipart body: %s", err)
+	}
+
+	return buffer.String(), writer.FormDataContentType(), nil
+}
@@ -1,9 +1,22 @@
 package resty
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"log"
+	"math/rand"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/PaesslerAG/jsonpath"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/jmespath/go-jmespath"
 )
 
 /* Using GetObjectAtKey, this function verifies the resulting
@@ -112,6 +125,152 @@ func GetObjectAtKey(data map[string]interface{}, path string, debug bool) (inter
 	return hash[part], nil
 }
 
+/* Counterpart of GetObjectAtKey, replaces the value at a slash separated path. Like there, numeric parts index into lists */
+func SetObjectAtKey(data map[string]interface{}, path string, value interface{}) error {
+	var current interface{} = data
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	seen := ""
+
+	for i, part := range parts {
+		last := i == len(parts)-1
+
+		switch typed := current.(type) {
+		case map[string]interface{}:
+			if last {
+				typed[part] = value
+				return nil
+			}
+			current = typed[part]
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(typed) {
+				return fmt.Errorf("SetObjectAtKey: List at '%s' has no index '%s'", seen, part)
+			}
+			if last {
+				typed[index] = value
+				return nil
+			}
+			current = typed[index]
+		default:
+			return fmt.Errorf("SetObjectAtKey: Object at '%s' in '%s' is not a map or list", seen, path)
+		}
+
+		seen += "/" + part
+	}
+
+	return nil
+}
+
+/* Blank the values found at paths, returns whether anything was redacted */
+func redactPaths(data interface{}, paths []string) (bool, error) {
+	if len(paths) == 0 {
+		return false, nil
+	}
+
+	hash, ok := data.(map[string]interface{})
+	if !ok {
+		return false, fmt.Errorf("Unable to apply redact_paths, the response is not a JSON object")
+	}
+
+	redacted := false
+	for _, path := range paths {
+		if _, err := GetObjectAtKey(hash, path, false); err != nil {
+			log.Printf("[RESTY] Nothing to redact at %s: %s", path, err)
+			continue
+		}
+		if err := SetObjectAtKey(hash, path, ""); err != nil {
+			return false, fmt.Errorf("Unable to redact %s: %s", path, err)
+		}
+		redacted = true
+	}
+	return redacted, nil
+}
+
+var redactHeaderRegexp = regexp.MustCompile(`(?mi)^((?:proxy-)?authorization|cookie|set-cookie):[^\r\n]*`)
+var redactUserinfoRegexp = regexp.MustCompile(`://[^/@\s]+@`)
+
+/* Hide credentials and the values of the configured headers from a request or response dump before it is logged */
+func redactDump(dump []byte, headers []string) string {
+	redacted := redactHeaderRegexp.ReplaceAll(dump, []byte("$1: REDACTED"))
+	redacted = redactUserinfoRegexp.ReplaceAll(redacted, []byte("://REDACTED@"))
+	for _, name := range headers {
+		header := regexp.MustCompile(`(?mi)^(` + regexp.QuoteMeta(name) + `):[^\r\n]*`)
+		redacted = header.ReplaceAll(redacted, []byte("$1: REDACTED"))
+	}
+	return string(redacted)
+}
+
+/* Collect every leaf of decoded JSON into result, keyed by its dotted path */
+func flattenResponse(data interface{}, prefix string, result map[string]interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch typed := data.(type) {
+	case map[string]interface{}:
+		for k, v := range typed {
+			flattenResponse(v, join(k), result)
+		}
+	case []interface{}:
+		for i, v := range typed {
+			flattenResponse(v, join(strconv.Itoa(i)), result)
+		}
+	case nil:
+		result[prefix] = ""
+	default:
+		result[prefix] = fmt.Sprintf("%v", typed)
+	}
+}
+
+/* Evaluate a jsonpath or jmespath query, falling back to GetObjectAtKey paths */
+func QueryObject(data interface{}, query string, language string, debug bool) (interface{}, error) {
+	if debug {
+		log.Printf("common.go:QueryObject: Evaluating %s query: %s", language, query)
+	}
+
+	switch language {
+	case "jsonpath":
+		return jsonpath.Get(query, data)
+	case "jmespath":
+		return jmespath.Search(query, data)
+	}
+
+	hash, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("QueryObject: Expected a JSON object to look up '%s', got '%T'", query, data)
+	}
+	return GetObjectAtKey(hash, query, debug)
+}
+
+/* Same as GetStringAtKey but using QueryObject */
+func QueryString(data interface{}, query string, language string, debug bool) (string, error) {
+	if language == "" {
+		if hash, ok := data.(map[string]interface{}); ok {
+			return GetStringAtKey(hash, query, debug)
+		}
+	}
+
+	res, err := QueryObject(data, query, language, debug)
+	if err != nil {
+		return "", err
+	}
+
+	/* Predicates return a list, a single match is good enough */
+	if list, ok := res.([]interface{}); ok && len(list) == 1 {
+		res = list[0]
+	}
+
+	switch res.(type) {
+	case string, float64:
+		return fmt.Sprintf("%v", res), nil
+	}
+
+	return "", fmt.Errorf("Result of query '%s' is not a JSON string or number (float64). The go fmt package says it is '%T'", query, res)
+}
+
 /* Handy helper to just dump the keys of a map into a slice */
 func GetKeys(hash map[string]interface{}) []string {
 	keys := make([]string, 0)
@@ -120,3 +279,415 @@ func GetKeys(hash map[string]interface{}) []string {
 	}
 	return keys
 }
+
+/* Build the URL of a single object from the collection url and a relative path */
+func objectURL(url string, path string) string {
+	/* Default to the object living right below the collection */
+	if path == "" {
+		path = "{id}"
+	}
+
+	/* Absolute URLs are used untouched */
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+
+	/* The path goes in front of the query string of url, which is kept */
+	url, query := splitQuery(url)
+	path, path_query := splitQuery(path)
+	if query != "" && path_query != "" {
+		query += "&"
+	}
+	query += path_query
+
+	joined := strings.TrimRight(url, "/") + "/" + strings.TrimLeft(path, "/")
+	if query != "" {
+		joined += "?" + query
+	}
+	return joined
+}
+
+/* Split the raw query string off url, the rest is kept as is since placeholders like {id} must not be escaped */
+func splitQuery(url string) (string, string) {
+	u, err := neturl.Parse(url)
+	if err == nil && u.RawQuery == "" {
+		return url, ""
+	}
+
+	i := strings.Index(url, "?")
+	if i < 0 {
+		return url, ""
+	}
+	if err == nil {
+		return url[:i], u.RawQuery
+	}
+	/* placeholders in the host keep net/url from parsing it */
+	return url[:i], strings.SplitN(url[i+1:], "#", 2)[0]
+}
+
+/* Prefix relative URLs with the base URL of the provider */
+func resolveURL(base_url string, url string) string {
+	if base_url == "" || strings.Contains(url, "://") {
+		return url
+	}
+
+	return strings.TrimRight(base_url, "/") + "/" + strings.TrimLeft(url, "/")
+}
+
+/* Query attributes shared by the resource and the data sources */
+func querySchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"query": {
+			Type:        schema.TypeMap,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+			Description: "Query parameters added to the url, merged with the provider ones",
+			Optional:    true,
+		},
+		"query_param": {
+			Type:        schema.TypeList,
+			Description: "Query parameters added to the url, repeat a name to send it several times",
+			Optional:    true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"name": {
+						Type:     schema.TypeString,
+						Required: true,
+					},
+					"value": {
+						Type:     schema.TypeString,
+						Required: true,
+					},
+				},
+			},
+		},
+	}
+}
+
+/* Combine provider and resource query parameters, query_param entries replace map values of the same name */
+func expandQuery(base map[string]string, query map[string]interface{}, params []interface{}) neturl.Values {
+	values := neturl.Values{}
+	for k, v := range base {
+		values.Set(k, v)
+	}
+	for k, v := range query {
+		values.Set(k, v.(string))
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range params {
+		param := p.(map[string]interface{})
+		name := param["name"].(string)
+		if !seen[name] {
+			values.Del(name)
+			seen[name] = true
+		}
+		values.Add(name, param["value"].(string))
+	}
+
+	return values
+}
+
+/* Add query parameters to url, parameters already present in url are left untouched */
+func mergeQuery(url string, query neturl.Values) (string, error) {
+	if len(query) == 0 {
+		return url, nil
+	}
+
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return "", fmt.Errorf("Error parsing url %s: %s", url, err)
+	}
+
+	values := u.Query()
+	for k, v := range query {
+		if _, ok := values[k]; !ok {
+			values[k] = v
+		}
+	}
+	u.RawQuery = values.Encode()
+
+	return u.String(), nil
+}
+
+var templateRegexp = regexp.MustCompile(`\{(id|response\.[^{}\s"]+|env\.[A-Za-z_][A-Za-z0-9_]*)\}`)
+
+/* Substitute {id}, {response.path.to.field} and {env.NAME} placeholders, failing on any which can't be resolved */
+func renderTemplate(s string, id string, response string) (string, error) {
+	var parsed map[string]interface{}
+	var failure error
+
+	result := templateRegexp.ReplaceAllStringFunc(s, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		value, err := "", error(nil)
+
+		switch {
+		case name == "id":
+			if id == "" {
+				err = fmt.Errorf("the object has no id yet")
+			}
+			value = id
+		case strings.HasPrefix(name, "env."):
+			var ok bool
+			if value, ok = os.LookupEnv(strings.TrimPrefix(name, "env.")); !ok {
+				err = fmt.Errorf("environment variable is not set")
+			}
+		default:
+			if parsed == nil {
+				if err = json.Unmarshal([]byte(response), &parsed); err != nil {
+					err = fmt.Errorf("no JSON response available")
+					break
+				}
+			}
+			path := strings.Replace(strings.TrimPrefix(name, "response."), ".", "/", -1)
+			value, err = GetStringAtKey(parsed, path, false)
+		}
+
+		if err != nil && failure == nil {
+			failure = fmt.Errorf("Unable to resolve placeholder %s: %s", placeholder, err)
+		}
+		return value
+	})
+
+	if failure != nil {
+		return "", failure
+	}
+	return result, nil
+}
+
+/* Check a status code against a list of codes or ranges such as "2xx" */
+func statusExpected(code int, expected []string) bool {
+	status := fmt.Sprintf("%d", code)
+	for _, e := range expected {
+		if e == status {
+			return true
+		}
+		if strings.HasSuffix(strings.ToLower(e), "xx") && len(e) == 3 && e[0] == status[0] {
+			return true
+		}
+	}
+	return false
+}
+
+/* ValidateFunc for entries of expected_status_codes */
+func validateStatusCode(v interface{}, k string) (ws []string, errors []error) {
+	value := v.(string)
+	if ok, _ := regexp.MatchString(`^[1-5]([0-9]{2}|[xX]{2})$`, value); !ok {
+		errors = append(errors, fmt.Errorf("%q must be a status code like 201 or a range like 2xx, got: %s", k, value))
+	}
+	return
+}
+
+/* Convert a list from the schema into a slice of strings */
+func expandStringList(list []interface{}) []string {
+	result := make([]string, 0, len(list))
+	for _, v := range list {
+		result = append(result, v.(string))
+	}
+	return result
+}
+
+/* Hex encoded SHA-256 of a string */
+func hashString(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+}
+
+/* Last path segment of a URL such as a Location header, plain values are kept */
+func lastPathSegment(value string) string {
+	if !strings.Contains(value, "/") {
+		return value
+	}
+	if u, err := neturl.Parse(value); err == nil {
+		value = u.Path
+	}
+	value = strings.TrimRight(value, "/")
+	return value[strings.LastIndex(value, "/")+1:]
+}
+
+/* Handy helper to check if a slice holds a string */
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+/* Handy helper to check if a slice holds a number */
+func containsInt(list []int, value int) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+/* Work out how long to wait before the next attempt, honoring Retry-After */
+func retryWait(attempt int, resp *http.Response, min_wait int, max_wait int, jitter bool) time.Duration {
+	max := time.Duration(max_wait) * time.Second
+
+	if resp != nil {
+		if after := resp.Header.Get("Retry-After"); after != "" {
+			if seconds, err := strconv.Atoi(after); err == nil {
+				return capDuration(time.Duration(seconds)*time.Second, max)
+			}
+			if date, err := http.ParseTime(after); err == nil {
+				return capDuration(time.Until(date), max)
+			}
+		}
+	}
+
+	/* Otherwise double the minimum wait on every attempt */
+	wait := capDuration(time.Duration(min_wait)*time.Second<<uint(attempt), max)
+
+	/* Spread the retries between half and the full wait */
+	if jitter && wait > 1 {
+		wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)))
+	}
+
+	return wait
+}
+
+/* Keep a duration between zero and max */
+func capDuration(d time.Duration, max time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
+
+/* A single filter block used to pick items out of a list */
+type restyFilter struct {
+	name     string
+	operator string
+	value    string
+}
+
+/* Convert the filter blocks from the schema, an empty operator means eq */
+func expandFilters(list []interface{}) []restyFilter {
+	filters := make([]restyFilter, 0, len(list))
+	for _, f := range list {
+		m := f.(map[string]interface{})
+		filter := restyFilter{
+			name:     m["name"].(string),
+			operator: m["operator"].(string),
+			value:    m["value"].(string),
+		}
+		if filter.operator == "" {
+			filter.operator = "eq"
+		}
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
+/* Human readable form of a filter for error messages */
+func (f restyFilter) String() string {
+	if f.operator == "eq" {
+		return fmt.Sprintf("%s = %s", f.name, f.value)
+	}
+	return fmt.Sprintf("%s %s %s", f.name, f.operator, f.value)
+}
+
+/* Human readable form of all filters for error messages */
+func describeFilters(filters []restyFilter, mode string) string {
+	if mode == "" {
+		mode = "and"
+	}
+	parts := make([]string, 0, len(filters))
+	for _, f := range filters {
+		parts = append(parts, f.String())
+	}
+	return strings.Join(parts, " "+mode+" ")
+}
+
+/* List what the filtered fields hold in every item, to show why nothing matched */
+func describeCandidates(items []interface{}, filters []restyFilter, language string, debug bool) string {
+	candidates := make([]string, 0, len(items))
+	for _, item := range items {
+		fields := make([]string, 0, len(filters))
+		for _, f := range filters {
+			if child, err := QueryObject(item, f.name, language, debug); err == nil {
+				fields = append(fields, fmt.Sprintf("%s=%v", f.name, child))
+			} else {
+				fields = append(fields, fmt.Sprintf("%s=<missing>", f.name))
+			}
+		}
+		candidates = append(candidates, "{"+strings.Join(fields, ", ")+"}")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+/* Check an item against all filters, combined with and (default) or or */
+func matchFilters(item interface{}, filters []restyFilter, mode string, language string, debug bool) (bool, error) {
+	for _, f := range filters {
+		matched := false
+
+		/* A missing field never matches */
+		if child, err := QueryObject(item, f.name, language, debug); err == nil {
+			matched, err = compareFilter(child, f.operator, f.value)
+			if err != nil {
+				return false, fmt.Errorf("Filter %s: %s", f, err)
+			}
+		}
+
+		if mode == "or" && matched {
+			return true, nil
+		} else if mode != "or" && !matched {
+			return false, nil
+		}
+	}
+
+	return mode != "or", nil
+}
+
+/* Compare a decoded JSON value with the value of a filter */
+func compareFilter(child interface{}, operator string, value string) (bool, error) {
+	switch operator {
+	case "ne":
+		equal, err := compareFilter(child, "eq", value)
+		return !equal, err
+	case "regex":
+		return regexp.MatchString(value, fmt.Sprintf("%v", child))
+	case "contains":
+		if list, ok := child.([]interface{}); ok {
+			for _, entry := range list {
+				if equal, _ := compareFilter(entry, "eq", value); equal {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		return strings.Contains(fmt.Sprintf("%v", child), value), nil
+	case "gt", "lt":
+		number, ok := child.(float64)
+		if !ok {
+			return false, fmt.Errorf("'%v' is not a number", child)
+		}
+		other, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false, fmt.Errorf("'%s' is not a number", value)
+		}
+		if operator == "gt" {
+			return number > other, nil
+		}
+		return number < other, nil
+	}
+
+	/* eq compares numbers and booleans by value, everything else as string */
+	switch typed := child.(type) {
+	case float64:
+		other, err := strconv.ParseFloat(value, 64)
+		return err == nil && typed == other, nil
+	case bool:
+		other, err := strconv.ParseBool(value)
+		return err == nil && typed == other, nil
+	case nil:
+		return value == "null", nil
+	}
+	return fmt.Sprintf("%v", child) == value, nil
+}
@@ -2,16 +2,17 @@ package resty
 
 import (
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 )
 
 func dataSourceREST() *schema.Resource {
-	return &schema.Resource{
+	r := &schema.Resource{
 		Read: restyRequest,
 
 		Schema: map[string]*schema.Schema{
 			"url": {
 				Type:        schema.TypeString,
-				Description: "The request URL",
+				Description: "The request URL, relative to the provider base_url unless absolute",
 				Required:    true,
 				ForceNew:    true,
 			},
@@ -36,8 +37,7 @@ func dataSourceREST() *schema.Resource {
 
 			"insecure": {
 				Type:        schema.TypeBool,
-				Description: "Validate Certificate",
-				Default:     false,
+				Description: "Skip certificate validation. Defaults to the provider setting",
 				Optional:    true,
 			},
 			"force_new": {
@@ -54,30 +54,76 @@ func dataSourceREST() *schema.Resource {
 				Default:     "id",
 				Optional:    true,
 			},
+			"id_strategy": {
+				Type:         schema.TypeString,
+				Description:  "How the id is determined: field, header, url, hash_of_response or static. Defaults to field",
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"field", "header", "url", "hash_of_response", "static"}, false),
+			},
+			"id_header": {
+				Type:        schema.TypeString,
+				Description: "Response header holding the id for id_strategy header, the last path segment is used. Defaults to Location",
+				Optional:    true,
+			},
+			"static_id": {
+				Type:        schema.TypeString,
+				Description: "The id used with id_strategy static",
+				Optional:    true,
+			},
+			"fail_on_missing_id": {
+				Type:        schema.TypeBool,
+				Description: "Fail when no id can be found instead of using a hash of the response",
+				Optional:    true,
+			},
 			"timeout": {
 				Type:        schema.TypeInt,
-				Description: "HTTP Timeout",
-				Default:     10,
+				Description: "HTTP Timeout. Defaults to the provider setting",
 				Optional:    true,
 			},
 			"retries": {
 				Type:        schema.TypeInt,
-				Description: "HTTP Retries",
-				Default:     1,
+				Description: "HTTP Retries. Defaults to the provider setting",
+				Optional:    true,
+			},
+			"retry_on_status": {
+				Type:        schema.TypeList,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "HTTP status codes that are retried. Defaults to 429, 502, 503 and 504",
+				Optional:    true,
+			},
+			"retry_min_wait": {
+				Type:        schema.TypeInt,
+				Description: "Seconds to wait before the first retry, doubled on every attempt. Defaults to 1",
+				Optional:    true,
+			},
+			"retry_max_wait": {
+				Type:        schema.TypeInt,
+				Description: "Maximum seconds to wait between retries. Defaults to 30",
+				Optional:    true,
+			},
+			"retry_jitter": {
+				Type:        schema.TypeBool,
+				Description: "Randomize the wait between retries. Defaults to true",
 				Optional:    true,
 			},
 			"username": {
 				Type:        schema.TypeString,
-				Description: "Basic Auth Username",
+				Description: "Basic Auth Username. Defaults to the provider setting",
 				Optional:    true,
 				Sensitive:   true,
 			},
 			"password": {
 				Type:        schema.TypeString,
-				Description: "Basic Auth Password",
+				Description: "Basic Auth Password. Defaults to the provider setting",
 				Optional:    true,
 				Sensitive:   true,
 			},
+			"expected_status_codes": {
+				Type:        schema.TypeList,
+				Elem:        &schema.Schema{Type: schema.TypeString, ValidateFunc: validateStatusCode},
+				Description: "HTTP status codes treated as success, ranges like 2xx are allowed. Defaults to the provider setting",
+				Optional:    true,
+			},
 			"debug": {
 				Type:        schema.TypeBool,
 				Description: "Print Debug Information",
@@ -88,7 +134,6 @@ func dataSourceREST() *schema.Resource {
 			"filter": {
 				Type:     schema.TypeList,
 				Optional: true,
-				MaxItems: 1,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"name": {
@@ -99,20 +144,72 @@ func dataSourceREST() *schema.Resource {
 							Type:     schema.TypeString,
 							Required: true,
 						},
+						"operator": {
+							Type:         schema.TypeString,
+							Description:  "One of eq, ne, regex, contains, gt or lt. Defaults to eq",
+							Optional:     true,
+							ValidateFunc: validation.StringInSlice([]string{"eq", "ne", "regex", "contains", "gt", "lt"}, false),
+						},
 					},
 				},
 			},
+			"filter_mode": {
+				Type:         schema.TypeString,
+				Description:  "Combine filters with and or or. Defaults to and",
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"and", "or"}, false),
+			},
+			"pagination": paginationSchema(),
 			"key": {
 				Type:        schema.TypeString,
 				Description: "Limit response conext by key",
 				Optional:    true,
 			},
+			"query_language": {
+				Type:         schema.TypeString,
+				Description:  "Language used by key, id_field and filter names, either jsonpath or jmespath. Defaults to plain keys and slash separated paths",
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"jsonpath", "jmespath"}, false),
+			},
 
 			"response": {
 				Type:        schema.TypeString,
 				Description: "Response from the request",
 				Computed:    true,
 			},
+			"sensitive_response": {
+				Type:        schema.TypeBool,
+				Description: "Keep the response out of plans and logs, storing it in response_sensitive instead of response and response_map",
+				Optional:    true,
+			},
+			"response_sensitive": {
+				Type:        schema.TypeString,
+				Description: "Response from the request when sensitive_response is set",
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"redact_paths": {
+				Type:        schema.TypeList,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Paths within the JSON response, e.g. auth/token, blanked before the response is stored",
+				Optional:    true,
+			},
+			"response_map": {
+				Type:        schema.TypeMap,
+				Description: "Every leaf of the JSON response keyed by its dotted path, e.g. config.foo",
+				Computed:    true,
+			},
+			"outputs": {
+				Type:        schema.TypeMap,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Named paths into the response, resolved like id_field",
+				Optional:    true,
+			},
+			"output_values": {
+				Type:        schema.TypeMap,
+				Description: "The values found at the paths declared in outputs",
+				Computed:    true,
+			},
 			"response_headers": {
 				Type:        schema.TypeMap,
 				Description: "Response Headers from the request",
@@ -120,6 +217,14 @@ func dataSourceREST() *schema.Resource {
 			},
 		},
 	}
+
+	for _, shared := range []map[string]*schema.Schema{querySchema(), bodySchema(), tlsSchema()} {
+		for k, v := range shared {
+			r.Schema[k] = v
+		}
+	}
+
+	return r
 }
 
 // reuse the request function from resource
@@ -0,0 +1,140 @@
+package resty
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+func dataSourceRESTList() *schema.Resource {
+	// same request options as the plain data source, but many results
+	s := dataSourceREST().Schema
+	delete(s, "response")
+	delete(s, "response_map")
+	delete(s, "outputs")
+	delete(s, "output_values")
+	delete(s, "sensitive_response")
+	delete(s, "response_sensitive")
+	delete(s, "id_strategy")
+	delete(s, "id_header")
+	delete(s, "static_id")
+	delete(s, "fail_on_missing_id")
+
+	s["key"].Description = "Limit response context by key, it has to point to a list"
+	s["responses"] = &schema.Schema{
+		Type:        schema.TypeList,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Description: "Every matching element of the list as JSON",
+		Computed:    true,
+	}
+	s["ids"] = &schema.Schema{
+		Type:        schema.TypeList,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Description: "The id_field of every matching element, empty if missing",
+		Computed:    true,
+	}
+
+	return &schema.Resource{
+		Read:   restyList,
+		Schema: s,
+	}
+}
+
+func restyList(d *schema.ResourceData, meta interface{}) error {
+
+	var response interface{}
+
+	url := d.Get("url").(string)
+	method := d.Get("method").(string)
+	debug := d.Get("debug").(bool)
+	id_field := d.Get("id_field").(string)
+	key := d.Get("key").(string)
+	query_language := d.Get("query_language").(string)
+	filters := expandFilters(d.Get("filter").([]interface{}))
+	filter_mode := d.Get("filter_mode").(string)
+	redact_paths := expandStringList(d.Get("redact_paths").([]interface{}))
+
+	url, err := restyTemplate(d, url)
+	if err != nil {
+		return err
+	}
+
+	data, err := requestData(d)
+	if err != nil {
+		return err
+	}
+
+	body, content_type, err := requestBody(d, data, true)
+	if err != nil {
+		return err
+	}
+
+	resp, response_body, err := restyPaginate(d, meta, method, url, body, content_type)
+	if err != nil {
+		return err
+	}
+
+	if err := checkStatus(d, meta, resp); err != nil {
+		return err
+	}
+
+	d.Set("response_headers", flattenHeaders(resp.Header))
+
+	if err := json.Unmarshal(response_body, &response); err != nil {
+		return fmt.Errorf("Error parsing response body as JSON: %s", err)
+	}
+
+	selected := response
+	if key != "" {
+		var ok bool
+		var err error
+		if selected, ok, err = selectKey(response, key, query_language, debug); err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("Response does not contain key: %s", key)
+		}
+	}
+
+	items, ok := selected.([]interface{})
+	if !ok {
+		return fmt.Errorf("Response is not a list, use key to point to one")
+	}
+
+	responses := make([]string, 0, len(items))
+	ids := make([]string, 0, len(items))
+
+	for _, item := range items {
+		if len(filters) > 0 {
+			matched, err := matchFilters(item, filters, filter_mode, query_language, debug)
+			if err != nil {
+				return err
+			}
+			if !matched {
+				continue
+			}
+		}
+
+		id, err := QueryString(item, id_field, query_language, debug)
+		if err != nil {
+			log.Printf("[RESTY] Item without id: %s", err)
+		}
+
+		if _, err := redactPaths(item, redact_paths); err != nil {
+			return err
+		}
+
+		out, _ := json.Marshal(item)
+		responses = append(responses, string(out))
+		ids = append(ids, id)
+	}
+
+	log.Printf("[RESTY] Found %d of %d items", len(responses), len(items))
+
+	d.Set("responses", responses)
+	d.Set("ids", ids)
+	d.SetId(url)
+
+	return nil
+}
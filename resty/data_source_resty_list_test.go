@@ -0,0 +1,159 @@
+package resty
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+)
+
+const testDataSourceListConfig = `
+data "resty_list" "test" {
+  url      = "%s/inventory"
+  key      = "items"
+  id_field = "name"
+}
+`
+
+func TestDataSourceList(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testDataSourceListConfig, mock.server.URL),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.resty_list.test", "ids.#", "2"),
+					resource.TestCheckResourceAttr("data.resty_list.test", "ids.0", "web"),
+					resource.TestCheckResourceAttr("data.resty_list.test", "ids.1", "db"),
+					resource.TestCheckResourceAttr("data.resty_list.test", "responses.1", `{"enabled":false,"meta":{"size":8},"name":"db"}`),
+				),
+			},
+		},
+	})
+}
+
+const testDataSourceListConfigFilter = `
+data "resty_list" "test" {
+  url      = "%s/inventory"
+  key      = "items"
+  id_field = "name"
+
+  filter {
+    name  = "enabled"
+    value = "true"
+  }
+}
+`
+
+func TestDataSourceList_withFilter(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testDataSourceListConfigFilter, mock.server.URL),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.resty_list.test", "ids.#", "1"),
+					resource.TestCheckResourceAttr("data.resty_list.test", "ids.0", "web"),
+				),
+			},
+		},
+	})
+}
+
+const testDataSourceListConfigNotList = `
+data "resty_list" "test" {
+  url = "%s/test"
+}
+`
+
+func TestDataSourceList_notList(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(testDataSourceListConfigNotList, mock.server.URL),
+				ExpectError: regexp.MustCompile("Response is not a list"),
+			},
+		},
+	})
+}
+
+const testDataSourceListConfigPaged = `
+data "resty_list" "test" {
+  url            = "%s/paged/%s"
+  query_language = "jmespath"
+  key            = "data.items"
+  id_field       = "name"
+
+  pagination {
+    type        = "%s"
+    items_key   = "data/items"
+    cursor_path = "next"
+    page_size   = 2
+    page_param  = "p"
+    start_page  = 0
+  }
+}
+`
+
+func TestDataSourceList_paginated(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	for _, paging := range []string{"link", "cursor", "offset", "page"} {
+		resource.UnitTest(t, resource.TestCase{
+			Providers: testProviders,
+			Steps: []resource.TestStep{
+				{
+					Config: fmt.Sprintf(testDataSourceListConfigPaged, mock.server.URL, paging, paging),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("data.resty_list.test", "ids.#", "5"),
+						resource.TestCheckResourceAttr("data.resty_list.test", "ids.4", "i5"),
+					),
+				},
+			},
+		})
+	}
+}
+
+const testDataSourceListConfigMaxPages = `
+data "resty_list" "test" {
+  url = "%s/paged/link"
+
+  pagination {
+    type      = "link"
+    items_key = "data/items"
+    max_pages = 2
+  }
+}
+`
+
+func TestDataSourceList_maxPages(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(testDataSourceListConfigMaxPages, mock.server.URL),
+				ExpectError: regexp.MustCompile(`Pagination stopped after max_pages \(2\) pages`),
+			},
+		},
+	})
+}
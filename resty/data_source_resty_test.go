@@ -0,0 +1,64 @@
+package resty
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+)
+
+const testDataSourceConfig = `
+data "resty" "test" {
+  url = "%s/test"
+}
+`
+
+func TestDataSource(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testDataSourceConfig, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("data.resty.test", "response", "{}"),
+			},
+		},
+	})
+}
+
+const testDataSourceConfigFilter = `
+data "resty" "test" {
+  url      = "%s/inventory"
+  key      = "items"
+  id_field = "name"
+  filter {
+    name  = "name"
+    value = "db"
+  }
+  outputs = {
+    size = "meta/size"
+  }
+}
+`
+
+func TestDataSource_withFilter(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testDataSourceConfigFilter, mock.server.URL),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.resty.test", "id", "db"),
+					resource.TestCheckResourceAttr("data.resty.test", "output_values.size", "8"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,126 @@
+package resty
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+// Implemented by both schema.ResourceData and schema.ResourceDiff
+type driftSource interface {
+	resourceGetter
+	Id() string
+}
+
+// A mismatch between the object fetched by Read and what was sent marks the
+// response as changing, so the next apply updates the object again
+func responseDriftDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if err := validateDriftOptions(d); err != nil {
+		return err
+	}
+
+	if !d.NewValueKnown("data") || !d.NewValueKnown("update_data") {
+		return nil
+	}
+
+	drifted, err := responseDrifted(d)
+	if err != nil || !drifted {
+		return err
+	}
+
+	return d.SetNewComputed("response")
+}
+
+// Drift can only be seen when read fetches the object, and only be fixed by a
+// real update, the fallback to the original request would create a duplicate
+func validateDriftOptions(d resourceGetter) error {
+	if len(d.Get("compare_paths").([]interface{})) == 0 && len(d.Get("ignore_response_paths").([]interface{})) == 0 {
+		return nil
+	}
+
+	if d.Get("read_method").(string) == "" && d.Get("read_path").(string) == "" {
+		return fmt.Errorf("compare_paths and ignore_response_paths need read_method or read_path to fetch the object")
+	}
+	if d.Get("update_method").(string) == "" && d.Get("update_path").(string) == "" && d.Get("update_data").(string) == "" {
+		return fmt.Errorf("compare_paths and ignore_response_paths need update_method, update_path or update_data to update the object in place")
+	}
+
+	return nil
+}
+
+// Compare what was sent with the object fetched by Read, restricted to
+// compare_paths and skipping ignore_response_paths
+func responseDrifted(d driftSource) (bool, error) {
+	compare_paths := expandStringList(d.Get("compare_paths").([]interface{}))
+	ignore_paths := expandStringList(d.Get("ignore_response_paths").([]interface{}))
+
+	// drift detection is opt-in, and there is nothing to compare before create
+	if d.Id() == "" || (len(compare_paths) == 0 && len(ignore_paths) == 0) {
+		return false, nil
+	}
+
+	sent := d.Get("update_data").(string)
+	if sent == "" {
+		var err error
+		if sent, err = requestData(d); err != nil {
+			return false, err
+		}
+	}
+
+	response := d.Get("response").(string)
+	if response == "" {
+		response = d.Get("response_sensitive").(string)
+	}
+
+	sent, err := renderTemplate(sent, d.Id(), response)
+	if err != nil {
+		return false, err
+	}
+
+	var desired, remote interface{}
+	if json.Unmarshal([]byte(sent), &desired) != nil || json.Unmarshal([]byte(response), &remote) != nil {
+		log.Printf("[RESTY] Skipping drift detection, data and response must both be JSON")
+		return false, nil
+	}
+
+	desired_map := make(map[string]interface{})
+	remote_map := make(map[string]interface{})
+	flattenResponse(desired, "", desired_map)
+	flattenResponse(remote, "", remote_map)
+
+	drifted := make([]string, 0)
+	for path, value := range desired_map {
+		if len(compare_paths) > 0 && !matchesPath(path, compare_paths) {
+			continue
+		}
+		if matchesPath(path, ignore_paths) {
+			continue
+		}
+		if remote_value, ok := remote_map[path]; !ok || remote_value != value {
+			drifted = append(drifted, path)
+		}
+	}
+
+	if len(drifted) == 0 {
+		return false, nil
+	}
+
+	sort.Strings(drifted)
+	log.Printf("[RESTY] Remote object drifted at: %s", strings.Join(drifted, ", "))
+
+	return true, nil
+}
+
+// Whether a dotted path is one of paths or nested below one of them
+func matchesPath(path string, paths []string) bool {
+	for _, p := range paths {
+		if path == p || strings.HasPrefix(path, p+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,267 @@
+package resty
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+var linkNextRegexp = regexp.MustCompile(`<([^>]*)>[^,]*;\s*rel="?next"?`)
+
+func paginationSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Description: "Follow every page of a paginated list endpoint and merge them",
+		Optional:    true,
+		MaxItems:    1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"type": {
+					Type:         schema.TypeString,
+					Description:  "One of link (RFC 5988 Link header), cursor, offset or page",
+					Required:     true,
+					ValidateFunc: validation.StringInSlice([]string{"link", "cursor", "offset", "page"}, false),
+				},
+				"items_key": {
+					Type:        schema.TypeString,
+					Description: "Slash separated path of the list in every page. Defaults to key, or the whole page",
+					Optional:    true,
+				},
+				"cursor_path": {
+					Type:        schema.TypeString,
+					Description: "Slash separated path of the next cursor or next URL in every page",
+					Optional:    true,
+				},
+				"cursor_param": {
+					Type:        schema.TypeString,
+					Description: "Query parameter carrying the cursor",
+					Default:     "cursor",
+					Optional:    true,
+				},
+				"offset_param": {
+					Type:        schema.TypeString,
+					Description: "Query parameter carrying the offset",
+					Default:     "offset",
+					Optional:    true,
+				},
+				"page_param": {
+					Type:        schema.TypeString,
+					Description: "Query parameter carrying the page number",
+					Default:     "page",
+					Optional:    true,
+				},
+				"limit_param": {
+					Type:        schema.TypeString,
+					Description: "Query parameter carrying the page size",
+					Default:     "limit",
+					Optional:    true,
+				},
+				"page_size": {
+					Type:        schema.TypeInt,
+					Description: "Items per page, required for offset pagination",
+					Optional:    true,
+				},
+				"start_page": {
+					Type:        schema.TypeInt,
+					Description: "Number of the first page",
+					Default:     1,
+					Optional:    true,
+				},
+				"max_pages": {
+					Type:        schema.TypeInt,
+					Description: "Fail rather than fetch more pages than this",
+					Default:     100,
+					Optional:    true,
+				},
+			},
+		},
+	}
+}
+
+// Send the request and, when pagination is configured, follow every further
+// page. The lists of all pages are merged into the body of the first one.
+func restyPaginate(d *schema.ResourceData, meta interface{}, method string, url string, data string, content_type string) (*http.Response, []byte, error) {
+
+	v, ok := d.GetOk("pagination")
+	if !ok {
+		return restyDo(d, meta, method, url, data, content_type, nil)
+	}
+
+	pagination := v.([]interface{})[0].(map[string]interface{})
+	debug := d.Get("debug").(bool)
+	paging := pagination["type"].(string)
+	page_size := pagination["page_size"].(int)
+	max_pages := pagination["max_pages"].(int)
+
+	items_key := pagination["items_key"].(string)
+	if items_key == "" && d.Get("query_language").(string) == "" {
+		items_key = d.Get("key").(string)
+	}
+
+	if paging == "offset" && page_size < 1 {
+		return nil, nil, fmt.Errorf("Offset pagination requires a page_size")
+	}
+
+	request_url := resolveURL(meta.(*ParentClient).base_url, url)
+	if paging == "offset" || paging == "page" {
+		request_url = pageURL(request_url, pagination, 0)
+	}
+
+	var first interface{}
+	var resp *http.Response
+	var response_body []byte
+	var err error
+	items := make([]interface{}, 0)
+
+	for page := 0; request_url != ""; page++ {
+		if page >= max_pages {
+			return nil, nil, fmt.Errorf("Pagination stopped after max_pages (%d) pages", max_pages)
+		}
+
+		resp, response_body, err = restyDo(d, meta, method, request_url, data, content_type, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := checkStatus(d, meta, resp); err != nil {
+			return nil, nil, err
+		}
+
+		var current interface{}
+		if err := json.Unmarshal(response_body, &current); err != nil {
+			return nil, nil, fmt.Errorf("Error parsing page %d as JSON: %s", page+1, err)
+		}
+
+		page_items, err := pageItems(current, items_key, debug)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Page %d: %s", page+1, err)
+		}
+
+		if page == 0 {
+			first = current
+		}
+		items = append(items, page_items...)
+
+		log.Printf("[RESTY] Page %d returned %d items", page+1, len(page_items))
+
+		request_url, err = nextPageURL(resp, current, pagination, page, len(page_items), debug)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// put every item into the first page
+	if items_key == "" {
+		first = items
+	} else if err := SetObjectAtKey(first.(map[string]interface{}), items_key, items); err != nil {
+		return nil, nil, err
+	}
+
+	merged, _ := json.Marshal(first)
+	return resp, merged, nil
+}
+
+// Locate the list of items in a page
+func pageItems(page interface{}, items_key string, debug bool) ([]interface{}, error) {
+	found := page
+	if items_key != "" {
+		hash, ok := page.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Expected a JSON object holding '%s'", items_key)
+		}
+		var err error
+		if found, err = GetObjectAtKey(hash, items_key, debug); err != nil {
+			return nil, err
+		}
+	}
+
+	items, ok := found.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Expected a list of items, use items_key to point to one")
+	}
+	return items, nil
+}
+
+// Work out the URL of the page after page, empty when this was the last one
+func nextPageURL(resp *http.Response, current interface{}, pagination map[string]interface{}, page int, count int, debug bool) (string, error) {
+	page_size := pagination["page_size"].(int)
+
+	switch pagination["type"].(string) {
+	case "link":
+		for _, link := range resp.Header["Link"] {
+			if match := linkNextRegexp.FindStringSubmatch(link); match != nil {
+				next, err := resp.Request.URL.Parse(match[1])
+				if err != nil {
+					return "", fmt.Errorf("Invalid next link '%s': %s", match[1], err)
+				}
+				return next.String(), nil
+			}
+		}
+		return "", nil
+
+	case "cursor":
+		hash, ok := current.(map[string]interface{})
+		if !ok {
+			return "", nil
+		}
+		cursor, err := GetObjectAtKey(hash, pagination["cursor_path"].(string), debug)
+		if err != nil || cursor == nil || cursor == "" {
+			return "", nil
+		}
+		value := fmt.Sprintf("%v", cursor)
+
+		// some APIs hand out the next URL rather than a token
+		if strings.Contains(value, "://") || strings.HasPrefix(value, "/") {
+			next, err := resp.Request.URL.Parse(value)
+			if err != nil {
+				return "", fmt.Errorf("Invalid next URL '%s': %s", value, err)
+			}
+			return next.String(), nil
+		}
+
+		next := *resp.Request.URL
+		query := next.Query()
+		query.Set(pagination["cursor_param"].(string), value)
+		next.RawQuery = query.Encode()
+		return next.String(), nil
+
+	default:
+		// offset and page stop at the first short page
+		if count == 0 || (page_size > 0 && count < page_size) {
+			return "", nil
+		}
+		return pageURL(resp.Request.URL.String(), pagination, page+1), nil
+	}
+}
+
+// Set the offset or page number query parameters for the given page
+func pageURL(request_url string, pagination map[string]interface{}, page int) string {
+	u, err := neturl.Parse(request_url)
+	if err != nil {
+		return request_url
+	}
+
+	page_size := pagination["page_size"].(int)
+	query := u.Query()
+
+	if pagination["type"].(string) == "offset" {
+		query.Set(pagination["offset_param"].(string), strconv.Itoa(page*page_size))
+	} else {
+		query.Set(pagination["page_param"].(string), strconv.Itoa(pagination["start_page"].(int)+page))
+	}
+
+	if page_size > 0 {
+		query.Set(pagination["limit_param"].(string), strconv.Itoa(page_size))
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String()
+}
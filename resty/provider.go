@@ -1,12 +1,22 @@
 package resty
 
 import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
 )
 
 func Provider() terraform.ResourceProvider {
-	return &schema.Provider{
+	provider := &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			"headers": &schema.Schema{
 				Type:        schema.TypeMap,
@@ -14,19 +24,124 @@ func Provider() terraform.ResourceProvider {
 				Optional:    true,
 				Description: "A map of headers to be used with every request",
 			},
+			"query": &schema.Schema{
+				Type:        schema.TypeMap,
+				Elem:        schema.TypeString,
+				Optional:    true,
+				Description: "A map of query parameters to be used with every request",
+			},
+			"base_url": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Base URL prepended to relative request URLs",
+			},
+			"timeout": &schema.Schema{
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     10,
+				Description: "HTTP Timeout",
+			},
+			"retries": &schema.Schema{
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     1,
+				Description: "HTTP Retries",
+			},
+			"insecure": &schema.Schema{
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Skip certificate validation",
+			},
+			"username": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Basic Auth Username",
+			},
+			"password": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Basic Auth Password",
+			},
+			"oauth2": &schema.Schema{
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Fetch a bearer token with the OAuth2 client credentials flow and send it with every request",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"token_url": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"client_id": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"client_secret": {
+							Type:      schema.TypeString,
+							Required:  true,
+							Sensitive: true,
+						},
+						"scopes": {
+							Type:     schema.TypeList,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+							Optional: true,
+						},
+						"audience": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"extra_params": {
+							Type:        schema.TypeMap,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Optional:    true,
+							Description: "Additional parameters sent to the token endpoint",
+						},
+					},
+				},
+			},
+			"expected_status_codes": &schema.Schema{
+				Type:        schema.TypeList,
+				Elem:        &schema.Schema{Type: schema.TypeString, ValidateFunc: validateStatusCode},
+				Optional:    true,
+				Description: "HTTP status codes treated as success by default, ranges like 2xx are allowed. Defaults to 200. The requests dealing with the object after create go by object_status_codes of the resource instead",
+			},
 		},
 		DataSourcesMap: map[string]*schema.Resource{
-			"resty": dataSourceREST(),
+			"resty":      dataSourceREST(),
+			"resty_list": dataSourceRESTList(),
 		},
 		ResourcesMap: map[string]*schema.Resource{
 			"resty": resourceREST(),
 		},
 		ConfigureFunc: configureProvider,
 	}
+
+	for k, v := range tlsSchema() {
+		provider.Schema[k] = v
+	}
+
+	return provider
 }
 
 type ParentClient struct {
-	headers map[string]string
+	headers               map[string]string
+	expected_status_codes []string
+	query                 map[string]string
+	base_url              string
+	timeout               int
+	retries               int
+	username              string
+	password              string
+	tls                   tlsSettings
+	token_source          oauth2.TokenSource
+
+	// http clients are shared by every resource with the same settings
+	lock       sync.Mutex
+	transports map[string]*http.Transport
+	clients    map[string]*http.Client
 }
 
 func configureProvider(d *schema.ResourceData) (interface{}, error) {
@@ -39,7 +154,112 @@ func configureProvider(d *schema.ResourceData) (interface{}, error) {
 		}
 	}
 
-	return &ParentClient{
-		headers: headers,
-	}, nil
+	query := make(map[string]string)
+	for k, v := range d.Get("query").(map[string]interface{}) {
+		query[k] = v.(string)
+	}
+
+	expected_status_codes := expandStringList(d.Get("expected_status_codes").([]interface{}))
+
+	// fail early on broken certificates
+	tls_settings, err := expandTLSSettings(d, tlsSettings{})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tls_settings.config(); err != nil {
+		return nil, err
+	}
+
+	parent := &ParentClient{
+		headers:               headers,
+		expected_status_codes: expected_status_codes,
+		query:                 query,
+		base_url:              d.Get("base_url").(string),
+		timeout:               d.Get("timeout").(int),
+		retries:               d.Get("retries").(int),
+		username:              d.Get("username").(string),
+		password:              d.Get("password").(string),
+		tls:                   tls_settings,
+		transports:            make(map[string]*http.Transport),
+		clients:               make(map[string]*http.Client),
+	}
+
+	if v, ok := d.GetOk("oauth2"); ok {
+		parent.token_source, err = oauth2TokenSource(parent, v.([]interface{})[0].(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return parent, nil
+}
+
+// Tokens are fetched lazily with the provider connection settings, then cached
+// and refreshed shortly before they expire.
+func oauth2TokenSource(parent *ParentClient, config map[string]interface{}) (oauth2.TokenSource, error) {
+	params := url.Values{}
+	if audience := config["audience"].(string); audience != "" {
+		params.Set("audience", audience)
+	}
+	for k, v := range config["extra_params"].(map[string]interface{}) {
+		params.Set(k, v.(string))
+	}
+
+	credentials := &clientcredentials.Config{
+		ClientID:       config["client_id"].(string),
+		ClientSecret:   config["client_secret"].(string),
+		TokenURL:       resolveURL(parent.base_url, config["token_url"].(string)),
+		Scopes:         expandStringList(config["scopes"].([]interface{})),
+		EndpointParams: params,
+	}
+
+	client, err := parent.httpClient(parent.tls, parent.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+	return credentials.TokenSource(ctx), nil
+}
+
+// Hand out a cached http.Client for the given settings. Clients differing only
+// in their timeout share one transport and therefore one connection pool.
+func (c *ParentClient) httpClient(settings tlsSettings, timeout int) (*http.Client, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	transport_key := settings.key()
+	client_key := fmt.Sprintf("%s,timeout=%d", transport_key, timeout)
+
+	if client, ok := c.clients[client_key]; ok {
+		return client, nil
+	}
+
+	transport, ok := c.transports[transport_key]
+	if !ok {
+		tls_config, err := settings.config()
+		if err != nil {
+			return nil, err
+		}
+		transport = &http.Transport{
+			TLSClientConfig: tls_config,
+			Proxy:           http.ProxyFromEnvironment,
+			Dial: (&net.Dialer{
+				Timeout:   time.Second * 30,
+				KeepAlive: time.Second * 30,
+			}).Dial,
+			TLSHandshakeTimeout:   time.Second * 10,
+			ResponseHeaderTimeout: time.Second * 10,
+			MaxIdleConnsPerHost:   10,
+		}
+		c.transports[transport_key] = transport
+	}
+
+	client := &http.Client{
+		Timeout:   time.Second * time.Duration(timeout),
+		Transport: transport,
+	}
+	c.clients[client_key] = client
+
+	return client, nil
 }
@@ -1,6 +1,7 @@
 package resty
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
@@ -16,3 +17,33 @@ func TestProvider(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 }
+
+func TestProvider_sharedClients(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, Provider().(*schema.Provider).Schema, map[string]interface{}{})
+
+	meta, err := configureProvider(d)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	parent := meta.(*ParentClient)
+
+	client := func(insecure bool, timeout int) *http.Client {
+		c, err := parent.httpClient(tlsSettings{insecure: insecure}, timeout)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return c
+	}
+
+	if client(false, 10) != client(false, 10) {
+		t.Fatalf("expected the same client for the same settings")
+	}
+
+	if client(false, 10).Transport != client(false, 30).Transport {
+		t.Fatalf("expected clients with different timeouts to share a transport")
+	}
+
+	if client(false, 10).Transport == client(true, 10).Transport {
+		t.Fatalf("expected insecure clients to use their own transport")
+	}
+}
@@ -2,32 +2,49 @@ package resty
 
 import (
 	"bytes"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
-	"net"
 	"net/http"
 	"net/http/httputil"
 	"strings"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-sdk/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 )
 
 func resourceREST() *schema.Resource {
-	return &schema.Resource{
-		Create: restyRequest,
+	r := &schema.Resource{
+		Create: restyCreate,
 		Read:   restyRead,
-		Update: restyRequest,
+		Update: restyUpdate,
 		Delete: restyDelete,
 		Exists: restyExists,
+		Importer: &schema.ResourceImporter{
+			State: restyImport,
+		},
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Version: 0,
+				Type:    resourceRESTV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: restyStateUpgradeV0,
+			},
+		},
+		// drift detection goes first, the hashed secrets check relies on it
+		CustomizeDiff: customdiff.All(
+			responseDriftDiff,
+			hashedSecretsDiff,
+		),
 
 		Schema: map[string]*schema.Schema{
 			"url": {
 				Type:        schema.TypeString,
-				Description: "The request URL",
+				Description: "The request URL, relative to the provider base_url unless absolute. Supports {id}, {response.path} and {env.NAME} placeholders, as do headers and data. Existing objects are imported with <url>|<id>, the url is required",
 				Required:    true,
 				ForceNew:    true,
 			},
@@ -51,8 +68,7 @@ func resourceREST() *schema.Resource {
 
 			"insecure": {
 				Type:        schema.TypeBool,
-				Description: "Validate Certificate",
-				Default:     false,
+				Description: "Skip certificate validation. Defaults to the provider setting",
 				Optional:    true,
 			},
 			"force_new": {
@@ -69,30 +85,87 @@ func resourceREST() *schema.Resource {
 				Default:     "id",
 				Optional:    true,
 			},
+			"id_strategy": {
+				Type:         schema.TypeString,
+				Description:  "How the id is determined: field, header, url, hash_of_response or static. Defaults to field",
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"field", "header", "url", "hash_of_response", "static"}, false),
+			},
+			"id_header": {
+				Type:        schema.TypeString,
+				Description: "Response header holding the id for id_strategy header, the last path segment is used. Defaults to Location",
+				Optional:    true,
+			},
+			"static_id": {
+				Type:        schema.TypeString,
+				Description: "The id used with id_strategy static",
+				Optional:    true,
+			},
+			"fail_on_missing_id": {
+				Type:        schema.TypeBool,
+				Description: "Fail when no id can be found instead of using a hash of the response",
+				Optional:    true,
+			},
 			"timeout": {
 				Type:        schema.TypeInt,
-				Description: "HTTP Timeout",
-				Default:     10,
+				Description: "HTTP Timeout. Defaults to the provider setting",
 				Optional:    true,
 			},
 			"retries": {
 				Type:        schema.TypeInt,
-				Description: "HTTP Retries",
-				Default:     1,
+				Description: "HTTP Retries. Defaults to the provider setting",
+				Optional:    true,
+			},
+			"retry_on_status": {
+				Type:        schema.TypeList,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "HTTP status codes that are retried. Defaults to 429, 502, 503 and 504",
+				Optional:    true,
+			},
+			"retry_min_wait": {
+				Type:        schema.TypeInt,
+				Description: "Seconds to wait before the first retry, doubled on every attempt. Defaults to 1",
+				Optional:    true,
+			},
+			"retry_max_wait": {
+				Type:        schema.TypeInt,
+				Description: "Maximum seconds to wait between retries. Defaults to 30",
+				Optional:    true,
+			},
+			"retry_jitter": {
+				Type:        schema.TypeBool,
+				Description: "Randomize the wait between retries. Defaults to true",
 				Optional:    true,
 			},
 			"username": {
 				Type:        schema.TypeString,
-				Description: "Basic Auth Username",
+				Description: "Basic Auth Username. Defaults to the provider setting",
 				Optional:    true,
 				Sensitive:   true,
 			},
 			"password": {
 				Type:        schema.TypeString,
-				Description: "Basic Auth Password",
+				Description: "Basic Auth Password. Defaults to the provider setting",
 				Optional:    true,
 				Sensitive:   true,
 			},
+			"hash_secrets": {
+				Type:        schema.TypeBool,
+				Description: "Store only a SHA-256 digest of data, sensitive_body, form, multipart values, update_data, password, headers and client_key in the state. They are then only sent on create and when changed, so the credentials can not be combined with the read, exists or destroy options",
+				Optional:    true,
+			},
+			"expected_status_codes": {
+				Type:        schema.TypeList,
+				Elem:        &schema.Schema{Type: schema.TypeString, ValidateFunc: validateStatusCode},
+				Description: "HTTP status codes treated as success by the request, ranges like 2xx are allowed. Defaults to the provider setting",
+				Optional:    true,
+			},
+			"object_status_codes": {
+				Type:        schema.TypeList,
+				Elem:        &schema.Schema{Type: schema.TypeString, ValidateFunc: validateStatusCode},
+				Description: "HTTP status codes treated as success by the read, update, destroy, exists, import and poll requests. Defaults to 2xx",
+				Optional:    true,
+			},
 			"debug": {
 				Type:        schema.TypeBool,
 				Description: "Print Debug Information",
@@ -103,7 +176,6 @@ func resourceREST() *schema.Resource {
 			"filter": {
 				Type:     schema.TypeList,
 				Optional: true,
-				MaxItems: 1,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"name": {
@@ -114,27 +186,207 @@ func resourceREST() *schema.Resource {
 							Type:     schema.TypeString,
 							Required: true,
 						},
+						"operator": {
+							Type:         schema.TypeString,
+							Description:  "One of eq, ne, regex, contains, gt or lt. Defaults to eq",
+							Optional:     true,
+							ValidateFunc: validation.StringInSlice([]string{"eq", "ne", "regex", "contains", "gt", "lt"}, false),
+						},
 					},
 				},
 			},
+			"filter_mode": {
+				Type:         schema.TypeString,
+				Description:  "Combine filters with and or or. Defaults to and",
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"and", "or"}, false),
+			},
+			"pagination": paginationSchema(),
 			"key": {
 				Type:        schema.TypeString,
 				Description: "Limit response conext by key",
 				Optional:    true,
 			},
+			"query_language": {
+				Type:         schema.TypeString,
+				Description:  "Language used by key, id_field and filter names, either jsonpath or jmespath. Defaults to plain keys and slash separated paths",
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"jsonpath", "jmespath"}, false),
+			},
 
 			"response": {
 				Type:        schema.TypeString,
 				Description: "Response from the request",
 				Computed:    true,
 			},
+			"sensitive_response": {
+				Type:        schema.TypeBool,
+				Description: "Keep the response out of plans and logs, storing it in response_sensitive instead of response and response_map",
+				Optional:    true,
+			},
+			"response_sensitive": {
+				Type:        schema.TypeString,
+				Description: "Response from the request when sensitive_response is set",
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"redact_paths": {
+				Type:        schema.TypeList,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Paths within the JSON response, e.g. auth/token, blanked before the response is stored",
+				Optional:    true,
+			},
+			"response_map": {
+				Type:        schema.TypeMap,
+				Description: "Every leaf of the JSON response keyed by its dotted path, e.g. config.foo",
+				Computed:    true,
+			},
+			"outputs": {
+				Type:        schema.TypeMap,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Named paths into the response, resolved like id_field",
+				Optional:    true,
+			},
+			"output_values": {
+				Type:        schema.TypeMap,
+				Description: "The values found at the paths declared in outputs",
+				Computed:    true,
+			},
 			"response_headers": {
 				Type:        schema.TypeMap,
 				Description: "Response Headers from the request",
 				Computed:    true,
 			},
+
+			"update_method": {
+				Type:        schema.TypeString,
+				Description: "The http request verb used on update, defaults to PUT when any update option is set",
+				Optional:    true,
+			},
+			"update_path": {
+				Type:        schema.TypeString,
+				Description: "Path appended to url on update, supports {id}. Defaults to {id}",
+				Optional:    true,
+			},
+			"update_data": {
+				Type:        schema.TypeString,
+				Description: "Data sent during the update request, defaults to data",
+				Optional:    true,
+				Sensitive:   true,
+			},
+
+			"read_method": {
+				Type:        schema.TypeString,
+				Description: "The http request verb used to refresh the object, defaults to GET when any read option is set",
+				Optional:    true,
+			},
+			"compare_paths": {
+				Type:        schema.TypeList,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Dotted paths of data, e.g. config.size, compared with the object fetched on read. Setting this or ignore_response_paths enables drift detection, which needs the read and update options",
+				Optional:    true,
+			},
+			"ignore_response_paths": {
+				Type:        schema.TypeList,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Dotted paths of data left out of drift detection, e.g. fields the server rewrites",
+				Optional:    true,
+			},
+			"read_path": {
+				Type:        schema.TypeString,
+				Description: "Path appended to url to refresh the object, supports {id}. Defaults to {id}",
+				Optional:    true,
+			},
+			"exists_method": {
+				Type:         schema.TypeString,
+				Description:  "The http request verb used to check the object still exists, usually HEAD or GET. Defaults to read_method",
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"HEAD", "GET"}, false),
+			},
+
+			"poll": {
+				Type:        schema.TypeList,
+				Description: "Wait for an asynchronous operation to finish after create, update and destroy",
+				Optional:    true,
+				MaxItems:    1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"url": {
+							Type:        schema.TypeString,
+							Description: "URL to poll, supports {id}. Defaults to the Location header, then the read URL",
+							Optional:    true,
+						},
+						"status_key": {
+							Type:        schema.TypeString,
+							Description: "Path of the status field in the polled response",
+							Required:    true,
+						},
+						"success_values": {
+							Type:        schema.TypeList,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "Status values marking the operation as done",
+							Required:    true,
+						},
+						"failure_values": {
+							Type:        schema.TypeList,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "Status values marking the operation as failed",
+							Optional:    true,
+						},
+						"interval": {
+							Type:        schema.TypeInt,
+							Description: "Seconds between polls",
+							Default:     5,
+							Optional:    true,
+						},
+						"timeout": {
+							Type:        schema.TypeInt,
+							Description: "Seconds to wait for the operation to finish",
+							Default:     300,
+							Optional:    true,
+						},
+					},
+				},
+			},
+
+			"destroy_method": {
+				Type:        schema.TypeString,
+				Description: "The http request verb used on destroy, defaults to DELETE when any destroy option is set",
+				Optional:    true,
+			},
+			"destroy_path": {
+				Type:        schema.TypeString,
+				Description: "Path appended to url on destroy, supports {id}. Defaults to {id}",
+				Optional:    true,
+			},
+			"destroy_url": {
+				Type:          schema.TypeString,
+				Description:   "Full URL used on destroy instead of url and destroy_path, supports {id}",
+				Optional:      true,
+				ConflictsWith: []string{"destroy_path"},
+			},
+			"destroy_data": {
+				Type:        schema.TypeString,
+				Description: "Data sent during the destroy request",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"destroy_headers": {
+				Type:        schema.TypeMap,
+				Description: "Extra headers for the destroy request",
+				Optional:    true,
+			},
 		},
 	}
+
+	for _, shared := range []map[string]*schema.Schema{querySchema(), bodySchema(), tlsSchema()} {
+		for k, v := range shared {
+			r.Schema[k] = v
+		}
+	}
+	suppressHashedSecrets(r.Schema)
+
+	return r
 }
 
 func restyRead(d *schema.ResourceData, meta interface{}) error {
@@ -146,209 +398,919 @@ func restyRead(d *schema.ResourceData, meta interface{}) error {
 		return fmt.Errorf("Item not found")
 	}
 
-	return nil
-}
+	read_method := d.Get("read_method").(string)
+	read_path := d.Get("read_path").(string)
 
-func restyDelete(d *schema.ResourceData, meta interface{}) error {
-	d.SetId("")
-	return nil
+	// without any read options we trust what is in the state
+	if read_method == "" && read_path == "" {
+		return nil
+	}
+
+	if read_method == "" {
+		read_method = "GET"
+	}
+
+	url, err := restyTemplate(d, objectURL(d.Get("url").(string), read_path))
+	if err != nil {
+		return err
+	}
+
+	resp, response_body, err := restyDo(d, meta, read_method, url, "", "", nil)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		log.Printf("[RESTY] Object %s is gone, removing from state", id)
+		d.SetId("")
+		return nil
+	} else if err := checkObjectStatus(d, resp); err != nil {
+		return err
+	}
+
+	d.Set("response_headers", flattenHeaders(resp.Header))
+
+	_, stored, err := selectResponse(d, response_body)
+	if err != nil {
+		return err
+	}
+
+	return setResponse(d, stored)
 }
 
-func restyRequest(d *schema.ResourceData, meta interface{}) error {
+// Changes to anything else only affect how the response is stored or how the
+// following requests are sent, so they don't repeat the request
+var requestKeys = []string{
+	"method", "headers", "data", "body", "sensitive_body", "body_format", "form", "multipart",
+	"update_method", "update_path", "update_data",
+	"key", "filter", "filter_mode", "query_language",
+	"id_field", "id_strategy", "id_header", "static_id", "fail_on_missing_id",
+}
 
-	var req *http.Request
-	var err error
-	var id string
-	var output = make(map[string]interface{})
-	var response = make(map[string]interface{})
-	var response_headers = make(map[string]interface{})
+func restyUpdate(d *schema.ResourceData, meta interface{}) error {
 
-	url := d.Get("url").(string)
-	method := d.Get("method").(string)
-	additional_headers := d.Get("headers").(map[string]interface{})
-	data := d.Get("data").(string)
-	username := d.Get("username").(string)
-	password := d.Get("password").(string)
-	debug := d.Get("debug").(bool)
-	id_field := d.Get("id_field").(string)
-	key := d.Get("key").(string)
-	timeout := d.Get("timeout").(int)
-	retries := d.Get("retries").(int)
-	insecure := d.Get("insecure").(bool)
-	filters := d.Get("filter").([]interface{})
+	update_method := d.Get("update_method").(string)
+	update_path := d.Get("update_path").(string)
+	update_data := d.Get("update_data").(string)
 
-	base_headers := meta.(*ParentClient).headers
+	// drift detection asks for the request to be sent again
+	drifted, err := responseDrifted(d)
+	if err != nil {
+		return err
+	}
 
-	d.Set("id_field", id_field)
-	d.Set("insecure", insecure)
-	d.Set("timeout", timeout)
-	d.Set("retries", retries)
+	if !drifted && !d.HasChanges(requestKeys...) {
+		log.Printf("[RESTY] Nothing to send for this change")
+		if err := restyRead(d, meta); err != nil {
+			return err
+		}
+		if err := setResponse(d, storedResponse(d)); err != nil {
+			return err
+		}
+		return hashSecrets(d)
+	}
 
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
-		Proxy:           http.ProxyFromEnvironment,
-		Dial: (&net.Dialer{
-			Timeout:   time.Second * 30,
-			KeepAlive: time.Second * 30,
-		}).Dial,
-		TLSHandshakeTimeout:   time.Second * 10,
-		ResponseHeaderTimeout: time.Second * 10,
+	if update_data != "" {
+		if err := requireSecrets(d, "update_data"); err != nil {
+			return err
+		}
+	} else if err := requireSecrets(d, payloadKeys...); err != nil {
+		return err
 	}
 
-	client := &http.Client{
-		Timeout:   time.Second * time.Duration(timeout),
-		Transport: transport,
+	// without any update options simply repeat the original request
+	if update_method == "" && update_path == "" && update_data == "" {
+		return restyCreate(d, meta)
 	}
 
-	buffer := bytes.NewBuffer([]byte(data))
-	if data == "" {
-		req, err = http.NewRequest(method, url, nil)
-	} else {
-		req, err = http.NewRequest(method, url, buffer)
-		req.Header.Set("Content-Type", "application/json")
+	if update_method == "" {
+		update_method = "PUT"
 	}
 
+	url, err := restyTemplate(d, objectURL(d.Get("url").(string), update_path))
 	if err != nil {
-		return fmt.Errorf("Error building request: %s", err)
+		return err
 	}
 
-	// set base headers
-	if len(base_headers) > 0 {
-		for k, v := range base_headers {
-			req.Header.Set(k, v)
+	if update_data == "" {
+		if update_data, err = requestData(d); err != nil {
+			return err
 		}
 	}
 
-	// allow override of additional headers
-	if len(additional_headers) > 0 {
-		for k, v := range additional_headers {
-			req.Header.Set(k, v.(string))
-		}
+	body, content_type, err := requestBody(d, update_data, true)
+	if err != nil {
+		return err
 	}
 
-	if username != "" && password != "" {
-		req.SetBasicAuth(username, password)
+	resp, response_body, err := restyDo(d, meta, update_method, url, body, content_type, nil)
+	if err != nil {
+		return err
 	}
 
-	if debug {
-		reqDump, _ := httputil.DumpRequest(req, true)
-		log.Printf("[RESTY] Request:\n%s", string(reqDump))
+	if err := checkObjectStatus(d, resp); err != nil {
+		return err
 	}
 
-	resp, err := client.Do(req)
+	d.Set("response_headers", flattenHeaders(resp.Header))
 
-	if err != nil {
-		log.Printf("[RESTY] Error making request: %s", err)
-		for retries > 0 {
-			resp, err = client.Do(req)
-			if err != nil {
-				log.Printf("[RESTY] Error making request: %s", err)
-				retries -= 1
-			} else {
-				break
-			}
-		}
+	if len(response_body) > 0 {
+		_, stored, err := selectResponse(d, response_body)
 		if err != nil {
-			return fmt.Errorf("Error making a request: %s", err)
+			return err
+		}
+		if err := setResponse(d, stored); err != nil {
+			return err
 		}
 	}
 
-	defer resp.Body.Close()
+	if err := restyPoll(d, meta, resp, false); err != nil {
+		return err
+	}
 
-	if debug {
-		respDump, _ := httputil.DumpResponse(resp, true)
-		log.Printf("[RESTY] Response:\n%s", string(respDump))
+	if err := restyRead(d, meta); err != nil {
+		return err
 	}
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("HTTP request error. Response code: %d", resp.StatusCode)
+	return hashSecrets(d)
+}
+
+func restyDelete(d *schema.ResourceData, meta interface{}) error {
+
+	destroy_method := d.Get("destroy_method").(string)
+	destroy_path := d.Get("destroy_path").(string)
+	destroy_url := d.Get("destroy_url").(string)
+	destroy_data := d.Get("destroy_data").(string)
+	destroy_headers := d.Get("destroy_headers").(map[string]interface{})
+
+	// without any destroy options we only forget about the object
+	if destroy_method == "" && destroy_path == "" && destroy_url == "" {
+		d.SetId("")
+		return nil
 	}
 
-	response_body, err := ioutil.ReadAll(resp.Body)
+	if destroy_method == "" {
+		destroy_method = "DELETE"
+	}
+
+	url := destroy_url
+	if url == "" {
+		url = objectURL(d.Get("url").(string), destroy_path)
+	}
+	url, err := restyTemplate(d, url)
 	if err != nil {
-		return fmt.Errorf("Error while reading response body. %s", err)
+		return err
 	}
 
-	if debug {
-		log.Printf("[RESTY] Response Body:\n%s\n", string(response_body))
+	body, content_type, err := requestBody(d, destroy_data, false)
+	if err != nil {
+		return err
+	}
+
+	resp, _, err := restyDo(d, meta, destroy_method, url, body, content_type, destroy_headers)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		log.Printf("[RESTY] Object %s already gone. Response code: %d", d.Id(), resp.StatusCode)
+	} else if err := checkObjectStatus(d, resp); err != nil {
+		return err
+	} else if err := restyPoll(d, meta, resp, true); err != nil {
+		return err
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// Read of the data source
+func restyRequest(d *schema.ResourceData, meta interface{}) error {
+	_, err := restySend(d, meta)
+	return err
+}
+
+// Send the request and store the selected part of the response along with
+// the id of the object
+func restySend(d *schema.ResourceData, meta interface{}) (*http.Response, error) {
+
+	url := d.Get("url").(string)
+	method := d.Get("method").(string)
+
+	d.Set("id_field", d.Get("id_field").(string))
+
+	url, err := restyTemplate(d, url)
+	if err != nil {
+		return nil, err
 	}
 
-	for k, v := range resp.Header {
-		// Concatenate according to RFC2616
-		// cf. https://www.w3.org/Protocols/rfc2616/rfc2616-sec4.html#sec4.2
-		response_headers[k] = strings.Join(v, ", ")
+	data, err := requestData(d)
+	if err != nil {
+		return nil, err
 	}
 
-	d.Set("response_headers", response_headers)
+	body, content_type, err := requestBody(d, data, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, response_body, err := restyPaginate(d, meta, method, url, body, content_type)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkStatus(d, meta, resp); err != nil {
+		return nil, err
+	}
+
+	d.Set("response_headers", flattenHeaders(resp.Header))
+
+	output, stored, err := selectResponse(d, response_body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setResponse(d, stored); err != nil {
+		return nil, err
+	}
+
+	id, err := restyID(d, resp, output, stored)
+	if err != nil {
+		return nil, err
+	}
+	d.SetId(id)
+
+	return resp, nil
+}
+
+// Keep the part of the response selected by key and filter, the whole
+// response without a key. Shared by create and read so both store the same
+// shape.
+func selectResponse(d *schema.ResourceData, response_body []byte) (map[string]interface{}, string, error) {
+
+	var output map[string]interface{}
+	var response = make(map[string]interface{})
+
+	debug := d.Get("debug").(bool)
+	key := d.Get("key").(string)
+	query_language := d.Get("query_language").(string)
+	filters := expandFilters(d.Get("filter").([]interface{}))
+	filter_mode := d.Get("filter_mode").(string)
+	log_body := logResponseBody(d)
+
+	stored := string(response_body)
 
 	if string(response_body) != "" {
 		err := json.Unmarshal([]byte(response_body), &response)
 		if err != nil {
 			log.Printf("[RESTY] Non-Fatal error parsing body as JSON")
-			d.SetId(time.Now().UTC().String())
-			d.Set("response", string(response_body))
 		} else {
+			output = make(map[string]interface{})
 			if key != "" {
-				if _, ok := response[key]; ok {
+				selected, ok, err := selectKey(response, key, query_language, debug)
+				if err != nil {
+					return nil, "", err
+				}
+				// predicates return a list, a single match is good enough
+				if list, is_list := selected.([]interface{}); is_list && len(list) == 1 && query_language != "" && len(filters) == 0 {
+					selected = list[0]
+				}
+				if ok {
 					log.Printf("[RESTY] Key exists")
-					if tmp, ok := response[key].(map[string]interface{}); ok {
+					if tmp, ok := selected.(map[string]interface{}); ok {
 						log.Printf("[RESTY] It points to a map")
 						output = tmp
-					} else if tmp, ok := response[key].([]interface{}); ok {
+					} else if tmp, ok := selected.([]interface{}); ok {
 						log.Printf("[RESTY] It points to a list")
 
-						var name string
-						var value string
-
-						if filters != nil {
+						if len(filters) > 0 {
 							log.Printf("[RESTY] Filter response requested")
 
-							// todo: clean this doesn't need to be a loop
-							for _, cf := range filters {
-								customFilterMap := cf.(map[string]interface{})
-								name = customFilterMap["name"].(string)
-								value = customFilterMap["value"].(string)
-							}
-						Done:
 							for _, parent := range tmp {
-								for entry, child := range parent.(map[string]interface{}) {
-									if entry == name && child == value {
+								item, ok := parent.(map[string]interface{})
+								if !ok {
+									continue
+								}
+								matched, err := matchFilters(item, filters, filter_mode, query_language, debug)
+								if err != nil {
+									return nil, "", err
+								}
+								if matched {
+									if log_body {
 										log.Printf("[RESTY] Found the item: %s", parent)
-										output = parent.(map[string]interface{})
-										break Done
 									}
+									output = item
+									break
 								}
 							}
 							if len(output) == 0 {
-								return fmt.Errorf("Response no filter match for: %s = %s", name, value)
+								return nil, "", fmt.Errorf("Response no filter match for: %s. Candidates: %s",
+									describeFilters(filters, filter_mode), describeCandidates(tmp, filters, query_language, debug))
 							}
 						} else {
-							output = response[key].(map[string]interface{})
+							return nil, "", fmt.Errorf("Response key %s points to a list, use a filter to select an item", key)
 						}
+					} else {
+						return nil, "", fmt.Errorf("Response key %s does not point to an object", key)
 					}
 				} else {
-					return fmt.Errorf("Response does not contain key: %s", key)
+					return nil, "", fmt.Errorf("Response does not contain key: %s", key)
 				}
 			} else {
 				log.Printf("[RESTY] No key requested")
 				output = response
 			}
 
-			id, err = GetStringAtKey(output, id_field, debug)
 			out, _ := json.Marshal(output)
-			d.Set("response", string(out))
+			stored = string(out)
+		}
+	}
 
-			if id != "" {
-				d.SetId(id)
-			} else {
-				d.SetId(time.Now().UTC().String())
-			}
+	return output, stored, nil
+}
+
+// Create sends the request like the data source does, then waits for the
+// remote operation and refreshes the object, which only the resource can do
+func restyCreate(d *schema.ResourceData, meta interface{}) error {
+
+	resp, err := restySend(d, meta)
+	if err != nil {
+		return err
+	}
+
+	if err := restyPoll(d, meta, resp, false); err != nil {
+		return err
+	}
+
+	if err := restyRead(d, meta); err != nil {
+		return err
+	}
+
+	return hashSecrets(d)
+}
+
+// Import an existing object using "<url>|<id>". A bare id doesn't say where
+// the object lives, and since url forces a new resource, importing without it
+// would plan to create the object a second time.
+func restyImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+
+	parts := strings.SplitN(d.Id(), "|", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("Unexpected format of import ID (%s), expected <url>|<id>. A bare <id> is not supported, the url of the resource is needed to find the object, e.g. https://api.example.com/items|42", d.Id())
+	}
+	url, id := parts[0], parts[1]
+
+	// start from the schema defaults so a plain configuration shows no diff
+	for k, v := range resourceREST().Schema {
+		if v.Default != nil {
+			d.Set(k, v.Default)
 		}
 	}
 
-	return restyRead(d, meta)
+	d.SetId(id)
+	d.Set("url", url)
+
+	object_url, err := restyTemplate(d, objectURL(url, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, response_body, err := restyDo(d, meta, "GET", object_url, "", "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkObjectStatus(d, resp); err != nil {
+		return nil, fmt.Errorf("Unable to import %s from %s. %s", id, url, err)
+	}
+
+	d.Set("response_headers", flattenHeaders(resp.Header))
+	if err := setResponse(d, normalizeResponse(response_body)); err != nil {
+		return nil, err
+	}
+
+	return []*schema.ResourceData{d}, nil
 }
 
 func restyExists(d *schema.ResourceData, meta interface{}) (bool, error) {
+
+	exists_method := d.Get("exists_method").(string)
+	read_method := d.Get("read_method").(string)
+	read_path := d.Get("read_path").(string)
+
+	// without a way to look the object up we have to assume it is there
+	if exists_method == "" && read_method == "" && read_path == "" {
+		return true, nil
+	}
+
+	if exists_method == "" {
+		exists_method = read_method
+	}
+	if exists_method == "" {
+		exists_method = "GET"
+	}
+
+	url, err := restyTemplate(d, objectURL(d.Get("url").(string), read_path))
+	if err != nil {
+		return false, err
+	}
+
+	resp, _, err := restyDo(d, meta, exists_method, url, "", "", nil)
+	if err != nil {
+		return false, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		log.Printf("[RESTY] Object %s does not exist. Response code: %d", d.Id(), resp.StatusCode)
+		return false, nil
+	} else if err := checkObjectStatus(d, resp); err != nil {
+		return false, err
+	}
+
 	return true, nil
 }
+
+// Render the placeholders of s using the id and last response of the object
+func restyTemplate(d *schema.ResourceData, s string) (string, error) {
+	return renderTemplate(s, d.Id(), storedResponse(d))
+}
+
+// The last response of the object, wherever sensitive_response put it
+func storedResponse(d *schema.ResourceData) string {
+	if v, ok := d.GetOk("response"); ok {
+		return v.(string)
+	} else if v, ok := d.GetOk("response_sensitive"); ok {
+		return v.(string)
+	}
+	return ""
+}
+
+// Work out the id of the object according to id_strategy. Unless
+// fail_on_missing_id is set, a missing id falls back to a hash of the response.
+func restyID(d *schema.ResourceData, resp *http.Response, output map[string]interface{}, response string) (string, error) {
+
+	var id string
+	var err error
+
+	id_strategy := d.Get("id_strategy").(string)
+	id_field := d.Get("id_field").(string)
+	fail_on_missing_id := d.Get("fail_on_missing_id").(bool)
+
+	switch id_strategy {
+	case "header":
+		id_header := d.Get("id_header").(string)
+		if id_header == "" {
+			id_header = "Location"
+		}
+		if id = lastPathSegment(resp.Header.Get(id_header)); id == "" {
+			err = fmt.Errorf("Response does not contain header: %s", id_header)
+		}
+	case "url":
+		id = resp.Request.URL.String()
+	case "hash_of_response":
+		id = hashString(response)
+	case "static":
+		if id = d.Get("static_id").(string); id == "" {
+			return "", fmt.Errorf("static_id is required with id_strategy static")
+		}
+	default:
+		if output == nil {
+			err = fmt.Errorf("Response is not a JSON object, unable to find id_field %s", id_field)
+		} else {
+			id, err = QueryString(output, id_field, d.Get("query_language").(string), d.Get("debug").(bool))
+		}
+	}
+
+	if err != nil {
+		if fail_on_missing_id {
+			return "", err
+		}
+		log.Printf("[RESTY] Using a hash of the response as id: %s", err)
+		return hashString(response), nil
+	}
+
+	return id, nil
+}
+
+// Fail unless the response code of the request is one of the expected status
+// codes of the resource, else of the provider. Defaults to 200.
+func checkStatus(d *schema.ResourceData, meta interface{}, resp *http.Response) error {
+	expected_status_codes := expandStringList(d.Get("expected_status_codes").([]interface{}))
+	if len(expected_status_codes) == 0 {
+		expected_status_codes = meta.(*ParentClient).expected_status_codes
+	}
+	if len(expected_status_codes) == 0 {
+		expected_status_codes = []string{"200"}
+	}
+	return statusError(resp, expected_status_codes)
+}
+
+// The requests dealing with the object once it exists answer differently
+// than the request which created it, they go by object_status_codes
+func checkObjectStatus(d *schema.ResourceData, resp *http.Response) error {
+	object_status_codes := []string{"2xx"}
+	if v := expandStringList(d.Get("object_status_codes").([]interface{})); len(v) > 0 {
+		object_status_codes = v
+	}
+	return statusError(resp, object_status_codes)
+}
+
+func statusError(resp *http.Response, expected []string) error {
+	if !statusExpected(resp.StatusCode, expected) {
+		return fmt.Errorf("HTTP request error. Response code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Look up key in the response, either as plain key or as query. A query
+// which can't be evaluated is an error rather than a missing key.
+func selectKey(response interface{}, key string, query_language string, debug bool) (interface{}, bool, error) {
+	if query_language == "" {
+		hash, ok := response.(map[string]interface{})
+		if !ok {
+			return nil, false, nil
+		}
+		selected, ok := hash[key]
+		return selected, ok, nil
+	}
+
+	selected, err := QueryObject(response, key, query_language, debug)
+	if err != nil {
+		return nil, false, fmt.Errorf("Error evaluating key %s: %s", key, err)
+	}
+	return selected, selected != nil, nil
+}
+
+// Block until the remote operation started by resp has finished. When deleting,
+// the object disappearing counts as success as well.
+func restyPoll(d *schema.ResourceData, meta interface{}, resp *http.Response, deleting bool) error {
+
+	polls := d.Get("poll").([]interface{})
+	if len(polls) == 0 || polls[0] == nil {
+		return nil
+	}
+
+	poll := polls[0].(map[string]interface{})
+	debug := d.Get("debug").(bool)
+	status_key := poll["status_key"].(string)
+	success_values := expandStringList(poll["success_values"].([]interface{}))
+	failure_values := expandStringList(poll["failure_values"].([]interface{}))
+
+	url := poll["url"].(string)
+	if url == "" {
+		if location, err := resp.Location(); err == nil {
+			url = location.String()
+		} else {
+			url = objectURL(d.Get("url").(string), d.Get("read_path").(string))
+		}
+	}
+	url, err := restyTemplate(d, url)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[RESTY] Polling %s until %s is one of %v", url, status_key, success_values)
+
+	conf := &resource.StateChangeConf{
+		Pending:      []string{"pending"},
+		Target:       []string{"done"},
+		Timeout:      time.Second * time.Duration(poll["timeout"].(int)),
+		PollInterval: time.Second * time.Duration(poll["interval"].(int)),
+		Refresh: func() (interface{}, string, error) {
+			resp, response_body, err := restyDo(d, meta, "GET", url, "", "", nil)
+			if err != nil {
+				return nil, "", err
+			}
+
+			if deleting && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone) {
+				return resp, "done", nil
+			} else if err := checkObjectStatus(d, resp); err != nil {
+				return nil, "", fmt.Errorf("Error while polling. %s", err)
+			}
+
+			response := make(map[string]interface{})
+			if err := json.Unmarshal(response_body, &response); err != nil {
+				return nil, "", fmt.Errorf("Error parsing polled response as JSON: %s", err)
+			}
+
+			status, err := GetStringAtKey(response, status_key, debug)
+			if err != nil {
+				return nil, "", err
+			}
+
+			if containsString(failure_values, status) {
+				return nil, "", fmt.Errorf("Remote operation failed with status: %s", status)
+			} else if containsString(success_values, status) {
+				return resp, "done", nil
+			}
+
+			log.Printf("[RESTY] Remote operation still in progress, status: %s", status)
+			return resp, "pending", nil
+		},
+	}
+
+	_, err = conf.WaitForState()
+	return err
+}
+
+// Send a single request using the connection settings of the resource.
+// The returned response body has already been read and closed.
+func restyDo(d *schema.ResourceData, meta interface{}, method string, url string, data string, content_type string, extra_headers map[string]interface{}) (*http.Response, []byte, error) {
+
+	var req *http.Request
+	var err error
+
+	parent := meta.(*ParentClient)
+
+	additional_headers := d.Get("headers").(map[string]interface{})
+	username := d.Get("username").(string)
+	password := d.Get("password").(string)
+
+	// hashed secrets are useless to the remote end
+	if err := requireSecrets(d, credentialKeys...); err != nil {
+		return nil, nil, err
+	}
+	debug := d.Get("debug").(bool)
+	log_body := logResponseBody(d)
+	log_request_body := logRequestBody(d)
+
+	// resource settings win over the provider ones
+	timeout := parent.timeout
+	if v, ok := d.GetOk("timeout"); ok {
+		timeout = v.(int)
+	}
+	retries := parent.retries
+	if v, ok := d.GetOkExists("retries"); ok {
+		retries = v.(int)
+	}
+	tls_settings, err := expandTLSSettings(d, parent.tls)
+	if err != nil {
+		return nil, nil, err
+	}
+	if username == "" && password == "" {
+		username = parent.username
+		password = parent.password
+	}
+
+	retry_on_status := []int{429, 502, 503, 504}
+	if v := d.Get("retry_on_status").([]interface{}); len(v) > 0 {
+		retry_on_status = make([]int, 0, len(v))
+		for _, code := range v {
+			retry_on_status = append(retry_on_status, code.(int))
+		}
+	}
+	retry_min_wait := 1
+	if v, ok := d.GetOkExists("retry_min_wait"); ok {
+		retry_min_wait = v.(int)
+	}
+	retry_max_wait := 30
+	if v, ok := d.GetOkExists("retry_max_wait"); ok {
+		retry_max_wait = v.(int)
+	}
+	retry_jitter := true
+	if v, ok := d.GetOkExists("retry_jitter"); ok {
+		retry_jitter = v.(bool)
+	}
+
+	url = resolveURL(parent.base_url, url)
+	url, err = mergeQuery(url, expandQuery(parent.query, d.Get("query").(map[string]interface{}), d.Get("query_param").([]interface{})))
+	if err != nil {
+		return nil, nil, err
+	}
+	base_headers := parent.headers
+
+	client, err := parent.httpClient(tls_settings, timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	buffer := bytes.NewBuffer([]byte(data))
+	if data == "" {
+		req, err = http.NewRequest(method, url, nil)
+	} else {
+		req, err = http.NewRequest(method, url, buffer)
+		if err == nil && content_type != "" {
+			req.Header.Set("Content-Type", content_type)
+		}
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error building request: %s", err)
+	}
+
+	// explicitly configured headers still win over the token
+	if parent.token_source != nil {
+		token, err := parent.token_source.Token()
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error fetching OAuth2 token: %s", err)
+		}
+		token.SetAuthHeader(req)
+	}
+
+	set_header := func(k string, v string) error {
+		value, err := restyTemplate(d, v)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(k, value)
+		return nil
+	}
+
+	// set base headers
+	for k, v := range base_headers {
+		if err := set_header(k, v); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// allow override of additional headers
+	for k, v := range additional_headers {
+		if err := set_header(k, v.(string)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// and finally the headers for this specific request
+	for k, v := range extra_headers {
+		if err := set_header(k, v.(string)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if username != "" && password != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	// any of the configured headers may carry a secret
+	configured_headers := make([]string, 0)
+	for _, headers := range []map[string]interface{}{additional_headers, extra_headers} {
+		for k := range headers {
+			configured_headers = append(configured_headers, k)
+		}
+	}
+	for k := range base_headers {
+		configured_headers = append(configured_headers, k)
+	}
+
+	if debug {
+		reqDump, _ := httputil.DumpRequest(req, log_request_body)
+		log.Printf("[RESTY] Request:\n%s", redactDump(reqDump, configured_headers))
+	}
+
+	var resp *http.Response
+
+	for attempt := 0; ; attempt++ {
+		// the previous attempt consumed the body, start over
+		if attempt > 0 && req.GetBody != nil {
+			req.Body, _ = req.GetBody()
+		}
+
+		resp, err = client.Do(req)
+		if err == nil && !containsInt(retry_on_status, resp.StatusCode) {
+			break
+		}
+
+		if err != nil {
+			log.Printf("[RESTY] Error making request: %s", err)
+		} else {
+			log.Printf("[RESTY] Retryable response code: %d", resp.StatusCode)
+		}
+
+		if attempt >= retries {
+			break
+		}
+
+		wait := retryWait(attempt, resp, retry_min_wait, retry_max_wait, retry_jitter)
+		if resp != nil {
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+
+		log.Printf("[RESTY] Retrying in %s (%d/%d)", wait, attempt+1, retries)
+		time.Sleep(wait)
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error making a request: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	if debug {
+		respDump, _ := httputil.DumpResponse(resp, log_body)
+		log.Printf("[RESTY] Response:\n%s", redactDump(respDump, configured_headers))
+	}
+
+	response_body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error while reading response body. %s", err)
+	}
+
+	if log_body {
+		log.Printf("[RESTY] Response Body:\n%s\n", string(response_body))
+	}
+
+	return resp, response_body, nil
+}
+
+// Store the response together with the attributes derived from it
+func setResponse(d *schema.ResourceData, response string) error {
+	sensitive_response := d.Get("sensitive_response").(bool)
+	redact_paths := expandStringList(d.Get("redact_paths").([]interface{}))
+	outputs := d.Get("outputs").(map[string]interface{})
+	output_values := make(map[string]interface{})
+
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(response), &decoded); err != nil {
+		if len(outputs) > 0 {
+			return fmt.Errorf("Unable to resolve outputs, the response is not JSON")
+		}
+		if len(redact_paths) > 0 && response != "" {
+			return fmt.Errorf("Unable to apply redact_paths, the response is not JSON")
+		}
+		storeResponse(d, response, sensitive_response)
+		d.Set("response_map", map[string]interface{}{})
+		d.Set("output_values", output_values)
+		return nil
+	}
+
+	redacted, err := redactPaths(decoded, redact_paths)
+	if err != nil {
+		return err
+	}
+	if redacted {
+		out, _ := json.Marshal(decoded)
+		response = string(out)
+	}
+	storeResponse(d, response, sensitive_response)
+
+	response_map := make(map[string]interface{})
+	if !sensitive_response {
+		flattenResponse(decoded, "", response_map)
+	}
+	d.Set("response_map", response_map)
+
+	query_language := d.Get("query_language").(string)
+	debug := d.Get("debug").(bool)
+	for name, path := range outputs {
+		value, err := QueryString(decoded, path.(string), query_language, debug)
+		if err != nil {
+			return fmt.Errorf("Unable to resolve output %s: %s", name, err)
+		}
+		output_values[name] = value
+	}
+	d.Set("output_values", output_values)
+
+	return nil
+}
+
+// Response bodies only show up in the debug logs when nothing in them is
+// secret, as marked by sensitive_response or redact_paths
+func logResponseBody(d *schema.ResourceData) bool {
+	_, sensitive_response := d.GetOk("sensitive_response")
+	_, redact_paths := d.GetOk("redact_paths")
+	return d.Get("debug").(bool) && !sensitive_response && !redact_paths
+}
+
+// Request bodies only show up in the debug logs when none of the secret
+// payload inputs is set
+func logRequestBody(d *schema.ResourceData) bool {
+	for _, key := range []string{"data", "sensitive_body", "form", "multipart", "update_data", "destroy_data"} {
+		if _, ok := d.GetOk(key); ok {
+			return false
+		}
+	}
+	return d.Get("debug").(bool)
+}
+
+// Store the response in response, or in response_sensitive so it stays out of plans
+func storeResponse(d *schema.ResourceData, response string, sensitive bool) {
+	if sensitive {
+		d.Set("response", "")
+		d.Set("response_sensitive", response)
+	} else {
+		d.Set("response", response)
+		d.Set("response_sensitive", "")
+	}
+}
+
+// Compact JSON bodies so they compare equal in state, anything else is kept as is
+func normalizeResponse(body []byte) string {
+	var response interface{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		log.Printf("[RESTY] Non-Fatal error parsing body as JSON")
+		return string(body)
+	}
+	out, _ := json.Marshal(response)
+	return string(out)
+}
+
+// Concatenate repeated response headers according to RFC2616
+// cf. https://www.w3.org/Protocols/rfc2616/rfc2616-sec4.html#sec4.2
+func flattenHeaders(header http.Header) map[string]interface{} {
+	headers := make(map[string]interface{})
+	for k, v := range header {
+		headers[k] = strings.Join(v, ", ")
+	}
+	return headers
+}
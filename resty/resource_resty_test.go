@@ -1,14 +1,32 @@
 package resty
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/terraform"
 )
 
@@ -16,6 +34,14 @@ type testHttpMock struct {
 	server *httptest.Server
 }
 
+type testCrudMock struct {
+	server  *httptest.Server
+	objects map[string]map[string]interface{}
+	next    int
+	queries []string
+	lock    sync.Mutex
+}
+
 const testResourceConfig = `
 resource "resty" "test" {
   url    = "%s/test"
@@ -211,6 +237,111 @@ func TestResourceGet_withFilter(t *testing.T) {
 	})
 }
 
+const testResourceConfigJMESPath = `
+resource "resty" "test" {
+  url     = "%s/filter"
+  headers = {
+    "Authorization" = "ZGVhZDpiZWVmCg=="
+  }
+  query_language = "jmespath"
+  key            = "content[?interesting=='value'] | [0]"
+  id_field       = "working"
+}
+`
+
+func TestResourceGet_withJMESPath(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigJMESPath, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "id", "yes"),
+			},
+		},
+	})
+}
+
+const testResourceConfigQueryKey = `
+resource "resty" "test" {
+  url            = "%s/inventory"
+  query_language = "%s"
+  key            = "%s"
+  id_field       = "name"
+}
+`
+
+func TestResourceGet_withPredicateKey(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				// a single match of the predicate is the object
+				Config: fmt.Sprintf(testResourceConfigQueryKey, mock.server.URL, "jmespath", "items[?name=='web']"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("resty.test", "id", "web"),
+					resource.TestCheckResourceAttr("resty.test", "response_map.meta.size", "2"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceGet_withBrokenKey(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(testResourceConfigQueryKey, mock.server.URL, "jsonpath", "$.items[?(@.name=='web')]"),
+				ExpectError: regexp.MustCompile("Error evaluating key"),
+			},
+		},
+	})
+}
+
+const testResourceConfigJSONPath = `
+resource "resty" "test" {
+  url     = "%s/filter"
+  headers = {
+    "Authorization" = "ZGVhZDpiZWVmCg=="
+  }
+  query_language = "jsonpath"
+  key            = "$.content"
+  id_field       = "$..working"
+  filter {
+    name  = "$.interesting"
+    value = "value"
+  }
+}
+`
+
+func TestResourceGet_withJSONPath(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigJSONPath, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "id", "yes"),
+			},
+		},
+	})
+}
+
 const testResourceConfigMissingFilter = `
 resource "resty" "test" {
   url     = "%s/filter"
@@ -242,36 +373,1741 @@ func TestResourceGet_withMissingFilter(t *testing.T) {
 	})
 }
 
-func initMockHttpServer() *testHttpMock {
-	Server := httptest.NewServer(
-		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+const testResourceConfigUpdateKey = `
+resource "resty" "test" {
+  url           = "%s/objects"
+  method        = "POST"
+  data          = "{\"item\": {\"name\": \"%s\"}}"
+  key           = "item"
+  id_strategy   = "header"
+  update_method = "PUT"
+  outputs = {
+    name = "name"
+  }
+}
+`
 
-			w.Header().Set("Content-Type", "application/json")
-			w.Header().Add("X-Is-Teapot", "Yes")
-			if r.URL.Path == "/test" {
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte("{}"))
-			} else if r.URL.Path == "/headers" {
-				if r.Header.Get("Authorization") == "ZGVhZDpiZWVmCg==" {
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte("{}"))
-				} else {
-					w.WriteHeader(http.StatusUnauthorized)
-				}
-			} else if r.URL.Path == "/filter" {
-				if r.Header.Get("Authorization") == "ZGVhZDpiZWVmCg==" {
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte("{\"content\": [{\"interesting\": \"no\", \"you\": \"failed\"},{\"interesting\": \"value\", \"working\": \"yes\"}]}"))
-				} else {
-					w.WriteHeader(http.StatusUnauthorized)
-				}
-			} else {
-				w.WriteHeader(http.StatusNotFound)
+func TestResourceUpdate_withKey(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigUpdateKey, mock.server.URL, "created"),
+				Check:  resource.TestCheckResourceAttr("resty.test", "output_values.name", "created"),
+			},
+			{
+				// the update response is narrowed down by key like the create one
+				Config: fmt.Sprintf(testResourceConfigUpdateKey, mock.server.URL, "updated"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("resty.test", "id", "1"),
+					resource.TestCheckResourceAttr("resty.test", "response", `{"name":"updated"}`),
+					resource.TestCheckResourceAttr("resty.test", "output_values.name", "updated"),
+				),
+			},
+		},
+	})
+}
+
+const testResourceConfigDestroy = `
+resource "resty" "test" {
+  url          = "%s/objects"
+  method       = "POST"
+  data         = "{\"name\": \"destroy\"}"
+  destroy_path = "{id}"
+}
+`
+
+func TestResourceDestroy(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		CheckDestroy: func(s *terraform.State) error {
+			if mock.count() != 0 {
+				return fmt.Errorf("remote object was not destroyed")
 			}
-		}),
-	)
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigDestroy, mock.server.URL),
+				Check: func(s *terraform.State) error {
+					if mock.count() != 1 {
+						return fmt.Errorf("remote object was not created")
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
 
-	return &testHttpMock{
-		server: Server,
-	}
+const testResourceConfigObjectQuery = `
+resource "resty" "test" {
+  url          = "%s/objects?api-version=1"
+  method       = "POST"
+  data         = "{\"name\": \"query\"}"
+  read_path    = "{id}"
+  destroy_path = "{id}?force=true"
+}
+`
+
+func TestResourceDestroy_withQuery(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		CheckDestroy: func(s *terraform.State) error {
+			if mock.count() != 0 {
+				return fmt.Errorf("remote object was not destroyed")
+			}
+			if last := mock.queries[len(mock.queries)-1]; last != "api-version=1&force=true" {
+				return fmt.Errorf("expected the query of url to be kept on destroy, got: %s", last)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigObjectQuery, mock.server.URL),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("resty.test", "id", "1"),
+					resource.TestCheckResourceAttr("resty.test", "response_map.name", "query"),
+					func(s *terraform.State) error {
+						for _, query := range mock.queries {
+							if query != "api-version=1" {
+								return fmt.Errorf("expected the query of url to be kept, got: %s", query)
+							}
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+const testResourceConfigDestroyGone = `
+resource "resty" "test" {
+  url            = "%s/objects"
+  method         = "POST"
+  data           = "{\"name\": \"gone\"}"
+  destroy_method = "DELETE"
+  destroy_url    = "%s/objects/missing-{id}"
+}
+`
+
+func TestResourceDestroy_alreadyGone(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigDestroyGone, mock.server.URL, mock.server.URL),
+			},
+		},
+	})
+}
+
+const testResourceConfigRead = `
+resource "resty" "test" {
+  url          = "%s/objects"
+  method       = "POST"
+  data         = "{\"name\": \"read\"}"
+  read_path    = "{id}"
+  destroy_path = "{id}"
+}
+`
+
+func TestResourceRead(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigRead, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "id", "1"),
+			},
+			{
+				// change the object behind terraform's back
+				PreConfig: func() {
+					mock.lock.Lock()
+					mock.objects["1"]["name"] = "changed"
+					mock.lock.Unlock()
+				},
+				Config: fmt.Sprintf(testResourceConfigRead, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "response", `{"id":"1","name":"changed"}`),
+			},
+			{
+				// remove the object behind terraform's back
+				PreConfig: func() {
+					mock.lock.Lock()
+					delete(mock.objects, "1")
+					mock.lock.Unlock()
+				},
+				Config: fmt.Sprintf(testResourceConfigRead, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "id", "2"),
+			},
+		},
+	})
+}
+
+const testResourceConfigReadKey = `
+resource "resty" "test" {
+  url       = "%[1]s/inventory"
+  read_path = "%[1]s/inventory"
+  key       = "items"
+  id_field  = "name"
+  filter {
+    name  = "name"
+    value = "web"
+  }
+  outputs = {
+    size = "meta/size"
+  }
+}
+`
+
+func TestResourceRead_withKey(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigReadKey, mock.server.URL),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("resty.test", "id", "web"),
+					resource.TestCheckResourceAttr("resty.test", "response", `{"enabled":true,"meta":{"size":2},"name":"web"}`),
+					resource.TestCheckResourceAttr("resty.test", "output_values.size", "2"),
+				),
+			},
+			{
+				// refreshing keeps the selected item rather than the whole body
+				Config:   fmt.Sprintf(testResourceConfigReadKey, mock.server.URL),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+const testResourceConfigExists = `
+resource "resty" "test" {
+  url           = "%s/objects"
+  method        = "POST"
+  data          = "{\"name\": \"exists\"}"
+  exists_method = "HEAD"
+}
+`
+
+func TestResourceExists(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigExists, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "id", "1"),
+			},
+			{
+				// remove the object behind terraform's back
+				PreConfig: func() {
+					mock.lock.Lock()
+					delete(mock.objects, "1")
+					mock.lock.Unlock()
+				},
+				Config: fmt.Sprintf(testResourceConfigExists, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "id", "2"),
+			},
+		},
+	})
+}
+
+const testResourceConfigUpdate = `
+resource "resty" "test" {
+  url         = "%s/objects"
+  method      = "POST"
+  data        = "{\"name\": \"%s\"}"
+  update_path = "{id}"
+}
+`
+
+func TestResourceUpdate(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigUpdate, mock.server.URL, "before"),
+				Check:  resource.TestCheckResourceAttr("resty.test", "response", `{"id":"1","name":"before"}`),
+			},
+			{
+				Config: fmt.Sprintf(testResourceConfigUpdate, mock.server.URL, "after"),
+				Check: func(s *terraform.State) error {
+					if mock.count() != 1 {
+						return fmt.Errorf("update created a new remote object")
+					}
+					return resource.TestCheckResourceAttr("resty.test", "response", `{"id":"1","name":"after"}`)(s)
+				},
+			},
+		},
+	})
+}
+
+const testResourceConfigImport = `
+resource "resty" "test" {
+  url    = "%s/objects"
+  method = "POST"
+  data   = "{\"name\": \"import\"}"
+}
+`
+
+func TestResourceImport(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigImport, mock.server.URL),
+			},
+			{
+				ResourceName:            "resty.test",
+				ImportState:             true,
+				ImportStateId:           fmt.Sprintf("%s/objects|1", mock.server.URL),
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"data", "method", "response_headers"},
+			},
+			{
+				ResourceName:  "resty.test",
+				ImportState:   true,
+				ImportStateId: fmt.Sprintf("%s/objects|2", mock.server.URL),
+				ExpectError:   regexp.MustCompile("Unable to import 2 .* Response code: 404"),
+			},
+			{
+				// without the url the object would be created again
+				ResourceName:  "resty.test",
+				ImportState:   true,
+				ImportStateId: "1",
+				ExpectError:   regexp.MustCompile(`Unexpected format of import ID \(1\), expected <url>\|<id>. A bare <id> is not supported`),
+			},
+		},
+	})
+}
+
+const testResourceConfigTemplate = `
+resource "resty" "test" {
+  url           = "%s/objects"
+  method        = "POST"
+  data          = "{\"name\": \"{env.RESTY_TEST_NAME}\"}"
+  update_path   = "{response.id}"
+  update_data   = "{\"name\": \"%s\"}"
+  destroy_path  = "{response.id}"
+}
+`
+
+func TestResourceTemplate(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	os.Setenv("RESTY_TEST_NAME", "templated")
+	defer os.Unsetenv("RESTY_TEST_NAME")
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		CheckDestroy: func(s *terraform.State) error {
+			if mock.count() != 0 {
+				return fmt.Errorf("remote object was not destroyed")
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(testResourceConfigTemplate, mock.server.URL+"/{env.RESTY_TEST_MISSING}", "x"),
+				ExpectError: regexp.MustCompile("Unable to resolve placeholder {env.RESTY_TEST_MISSING}"),
+			},
+			{
+				Config: fmt.Sprintf(testResourceConfigTemplate, mock.server.URL, "{env.RESTY_TEST_NAME}"),
+				Check:  resource.TestCheckResourceAttr("resty.test", "response_map.name", "templated"),
+			},
+			{
+				Config: fmt.Sprintf(testResourceConfigTemplate, mock.server.URL, "{env.RESTY_TEST_NAME}-{id}"),
+				Check:  resource.TestCheckResourceAttr("resty.test", "response_map.name", "templated-1"),
+			},
+			{
+				Config:      fmt.Sprintf(testResourceConfigTemplate, mock.server.URL, "{response.missing}"),
+				ExpectError: regexp.MustCompile("Unable to resolve placeholder {response.missing}"),
+			},
+		},
+	})
+}
+
+const testResourceConfigSensitive = `
+resource "resty" "sensitive" {
+  url                = "%[1]s/objects"
+  method             = "POST"
+  data               = "{\"name\": \"sensitive\", \"auth\": {\"token\": \"s3cr3t\"}}"
+  destroy_path       = "{id}"
+  sensitive_response = true
+  debug              = true
+  username           = "dead"
+  password           = "beef"
+  headers = {
+    Cookie    = "session=s3cr3t"
+    X-Api-Key = "k3y"
+  }
+}
+
+resource "resty" "redacted" {
+  url          = "%[1]s/objects"
+  method       = "POST"
+  data         = "{\"name\": \"redacted\", \"auth\": {\"token\": \"s3cr3t\"}}"
+  destroy_path = "{id}"
+  redact_paths = ["auth/token", "missing/path"]
+  debug        = true
+}
+
+resource "resty" "indexed" {
+  url          = "%[1]s/objects"
+  method       = "POST"
+  data         = "{\"items\": [{\"token\": \"s3cr3t\", \"name\": \"a\"}]}"
+  destroy_path = "{id}"
+  redact_paths = ["items/0/token"]
+}
+`
+
+func TestResourceSensitiveResponse(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	var logs bytes.Buffer
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					log.SetOutput(&logs)
+				},
+				Config: fmt.Sprintf(testResourceConfigSensitive, mock.server.URL),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("resty.sensitive", "response", ""),
+					resource.TestCheckResourceAttr("resty.sensitive", "response_map.%", "0"),
+					resource.TestMatchResourceAttr("resty.sensitive", "response_sensitive", regexp.MustCompile(`"token":"s3cr3t"`)),
+					resource.TestMatchResourceAttr("resty.redacted", "response", regexp.MustCompile(`^\{"auth":\{"token":""\},"id":"\d","name":"redacted"\}$`)),
+					resource.TestCheckResourceAttr("resty.redacted", "response_map.auth.token", ""),
+					resource.TestCheckResourceAttr("resty.indexed", "response_map.items.0.token", ""),
+					resource.TestCheckResourceAttr("resty.indexed", "response_map.items.0.name", "a"),
+					func(s *terraform.State) error {
+						log.SetOutput(ioutil.Discard)
+						for _, redacted := range []string{"Authorization: REDACTED", "Cookie: REDACTED", "X-Api-Key: REDACTED"} {
+							if !strings.Contains(logs.String(), redacted) {
+								return fmt.Errorf("debug log is missing %s", redacted)
+							}
+						}
+						for _, secret := range []string{"ZGVhZDpiZWVm", "Cookie: session", "X-Api-Key: k3y", `"token": "s3cr3t"`, `"name":"sensitive"`, `"name":"redacted"`} {
+							if strings.Contains(logs.String(), secret) {
+								return fmt.Errorf("debug log contains %s", secret)
+							}
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+const testResourceConfigHashSecrets = `
+resource "resty" "test" {
+  url          = "%s/objects"
+  method       = "POST"
+  data         = "{\"name\": \"%s\"}"
+  hash_secrets = true
+  username     = "dead"
+  password     = "%s"
+  headers = {
+    X-Secret = "%s"
+  }
+  outputs = {
+    %s = "name"
+  }
+}
+`
+
+func TestResourceHashSecrets(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigHashSecrets, mock.server.URL, "hashed", "beef", "s3cr3t", "name"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("resty.test", "data", hashString(`{"name": "hashed"}`)),
+					resource.TestCheckResourceAttr("resty.test", "password", hashString("beef")),
+					resource.TestCheckResourceAttr("resty.test", "headers.X-Secret", hashString("s3cr3t")),
+					resource.TestCheckResourceAttr("resty.test", "username", "dead"),
+					resource.TestCheckResourceAttr("resty.test", "output_values.name", "hashed"),
+				),
+			},
+			{
+				// the digests stand for the unchanged configuration
+				Config:   fmt.Sprintf(testResourceConfigHashSecrets, mock.server.URL, "hashed", "beef", "s3cr3t", "name"),
+				PlanOnly: true,
+			},
+			{
+				// outputs are resolved without sending the request again
+				Config: fmt.Sprintf(testResourceConfigHashSecrets, mock.server.URL, "hashed", "beef", "s3cr3t", "other"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("resty.test", "output_values.other", "hashed"),
+					func(*terraform.State) error {
+						if mock.next != 1 {
+							return fmt.Errorf("expected a single request, got %d objects", mock.next)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// the update could not authenticate
+				Config:      fmt.Sprintf(testResourceConfigHashSecrets, mock.server.URL, "changed", "beef", "s3cr3t", "name"),
+				ExpectError: regexp.MustCompile("password is only stored as a digest"),
+			},
+			{
+				Config: fmt.Sprintf(testResourceConfigHashSecrets, mock.server.URL, "changed", "cafe", "0th3r", "name"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("resty.test", "data", hashString(`{"name": "changed"}`)),
+					resource.TestCheckResourceAttr("resty.test", "password", hashString("cafe")),
+					resource.TestCheckResourceAttr("resty.test", "output_values.name", "changed"),
+				),
+			},
+		},
+	})
+}
+
+const testResourceConfigHashSecretsForm = `
+resource "resty" "test" {
+  url          = "%s/objects"
+  method       = "POST"
+  body_format  = "form"
+  update_data  = "name=updated"
+  hash_secrets = true
+  debug        = %t
+  form = {
+    token = "s3cr3t"
+  }
+}
+`
+
+func TestResourceHashSecrets_form(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigHashSecretsForm, mock.server.URL, false),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("resty.test", "form.token", hashString("s3cr3t")),
+					resource.TestCheckResourceAttr("resty.test", "update_data", hashString("name=updated")),
+				),
+			},
+			{
+				Config:   fmt.Sprintf(testResourceConfigHashSecretsForm, mock.server.URL, false),
+				PlanOnly: true,
+			},
+			{
+				// debug sends nothing, so the secrets aren't needed
+				Config: fmt.Sprintf(testResourceConfigHashSecretsForm, mock.server.URL, true),
+				Check:  resource.TestCheckResourceAttr("resty.test", "debug", "true"),
+			},
+		},
+	})
+}
+
+const testResourceConfigHashSecretsRead = `
+resource "resty" "test" {
+  url          = "%s/objects"
+  method       = "POST"
+  data         = "{\"name\": \"hashed\"}"
+  read_path    = "{id}"
+  hash_secrets = true
+  headers = {
+    X-Secret = "s3cr3t"
+  }
+}
+`
+
+func TestResourceHashSecrets_withReadPath(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				// refreshing would go out without the headers
+				Config:      fmt.Sprintf(testResourceConfigHashSecretsRead, mock.server.URL),
+				ExpectError: regexp.MustCompile("headers is only stored as a digest because of hash_secrets and can't be sent along read_path"),
+			},
+		},
+	})
+}
+
+const testResourceConfigDrift = `
+resource "resty" "test" {
+  url                   = "%s/objects"
+  method                = "POST"
+  data                  = "{\"name\": \"drift\", \"size\": 3, \"tags\": [\"a\"]}"
+  read_path             = "{id}"
+  update_method         = "PUT"
+  destroy_path          = "{id}"
+  compare_paths         = ["name", "size"]
+  ignore_response_paths = ["size"]
+}
+`
+
+func TestResourceDrift(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	change := func(key string, value interface{}) func() {
+		return func() {
+			mock.lock.Lock()
+			mock.objects["1"][key] = value
+			mock.lock.Unlock()
+		}
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigDrift, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "response_map.name", "drift"),
+			},
+			{
+				// volatile, ignored or not compared fields don't matter
+				PreConfig: func() {
+					change("updated_at", "now")()
+					change("size", 4)()
+					change("tags", []string{"b"})()
+				},
+				Config:   fmt.Sprintf(testResourceConfigDrift, mock.server.URL),
+				PlanOnly: true,
+			},
+			{
+				PreConfig:          change("name", "changed"),
+				Config:             fmt.Sprintf(testResourceConfigDrift, mock.server.URL),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: fmt.Sprintf(testResourceConfigDrift, mock.server.URL),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("resty.test", "response_map.name", "drift"),
+					resource.TestCheckResourceAttr("resty.test", "response_map.size", "3"),
+				),
+			},
+		},
+	})
+}
+
+const testResourceConfigDriftOptions = `
+resource "resty" "test" {
+  url           = "%s/objects"
+  method        = "POST"
+  data          = "{\"name\": \"drift\"}"
+  %s
+  compare_paths = ["name"]
+}
+`
+
+func TestResourceDrift_options(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				// response would never be refreshed
+				Config:      fmt.Sprintf(testResourceConfigDriftOptions, mock.server.URL, `update_method = "PUT"`),
+				ExpectError: regexp.MustCompile("need read_method or read_path"),
+			},
+			{
+				// the update would POST a second object
+				Config:      fmt.Sprintf(testResourceConfigDriftOptions, mock.server.URL, `read_path = "{id}"`),
+				ExpectError: regexp.MustCompile("need update_method, update_path or update_data"),
+			},
+		},
+	})
+}
+
+const testResourceConfigCreated = `
+resource "resty" "test" {
+  url                   = "%s/created"
+  method                = "POST"
+  expected_status_codes = ["200", "2xx"]
+}
+`
+
+func TestResourceCreated(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigCreated, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "response", `{"id":"created"}`),
+			},
+		},
+	})
+}
+
+const testResourceConfigCreatedProvider = `
+provider "resty" {
+  expected_status_codes = ["201"]
+}
+
+resource "resty" "test" {
+  url    = "%s/created"
+  method = "POST"
+}
+`
+
+func TestResourceCreated_providerDefault(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigCreatedProvider, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "id", "created"),
+			},
+		},
+	})
+}
+
+const testResourceConfigObjectStatus = `
+provider "resty" {
+  expected_status_codes = ["200"]
+}
+
+resource "resty" "test" {
+  url          = "%s/objects"
+  method       = "POST"
+  data         = "{\"name\": \"status\"}"
+  read_path    = "{id}"
+  destroy_path = "{id}"
+}
+`
+
+func TestResourceDestroy_providerStatus(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		CheckDestroy: func(s *terraform.State) error {
+			if mock.count() != 0 {
+				return fmt.Errorf("remote object was not destroyed")
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				// the provider setting is about the request, DELETE still answers 204
+				Config: fmt.Sprintf(testResourceConfigObjectStatus, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "response_map.name", "status"),
+			},
+		},
+	})
+}
+
+func TestResourceObjectStatus(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceREST().Schema, map[string]interface{}{
+		"url":                   "http://localhost/objects",
+		"expected_status_codes": []interface{}{"201"},
+	})
+	meta := &ParentClient{}
+
+	if err := checkStatus(d, meta, &http.Response{StatusCode: 201}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := checkStatus(d, meta, &http.Response{StatusCode: 204}); err == nil {
+		t.Fatalf("expected the request to only accept 201")
+	}
+	if err := checkObjectStatus(d, &http.Response{StatusCode: 204}); err != nil {
+		t.Fatalf("expected object requests to accept any 2xx, got: %s", err)
+	}
+
+	d.Set("object_status_codes", []interface{}{"200"})
+	if err := checkObjectStatus(d, &http.Response{StatusCode: 204}); err == nil {
+		t.Fatalf("expected object_status_codes to only accept 200")
+	}
+}
+
+const testResourceConfigBaseURL = `
+provider "resty" {
+  base_url = "%s"
+  username = "dead"
+  password = "beef"
+}
+
+resource "resty" "test" {
+  url = "/basic"
+}
+`
+
+func TestResourceGet_withBaseURL(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigBaseURL, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "response", "{}"),
+			},
+		},
+	})
+}
+
+const testResourceConfigQuery = `
+provider "resty" {
+  query = {
+    api-version = "2020-01-01"
+    fixed       = "provider"
+  }
+}
+
+resource "resty" "test" {
+  url = "%s/query?fixed=url"
+  query = {
+    name = "a b&c"
+    tag  = "replaced"
+  }
+  query_param {
+    name  = "tag"
+    value = "x"
+  }
+  query_param {
+    name  = "tag"
+    value = "y"
+  }
+}
+`
+
+func TestResourceGet_withQuery(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigQuery, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "response_map.query", "api-version=2020-01-01&fixed=url&name=a+b%26c&tag=x&tag=y"),
+			},
+		},
+	})
+}
+
+func TestResourceUpgradedState(t *testing.T) {
+	// state written while timeout, retries and insecure had defaults
+	raw := map[string]interface{}{
+		"id":               "created",
+		"url":              "http://localhost/created",
+		"method":           "POST",
+		"id_field":         "id",
+		"debug":            false,
+		"timeout":          float64(10),
+		"retries":          float64(1),
+		"insecure":         false,
+		"response":         `{"id":"created"}`,
+		"response_headers": map[string]interface{}{},
+	}
+
+	upgraded, err := restyStateUpgradeV0(raw, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for key := range resourceRESTV0Defaults {
+		if _, ok := upgraded[key]; ok {
+			t.Fatalf("expected the default of %s to be dropped, got: %v", key, upgraded[key])
+		}
+	}
+
+	state := &terraform.InstanceState{
+		ID: "created",
+		Attributes: map[string]string{
+			"id":                 "created",
+			"url":                "http://localhost/created",
+			"method":             "POST",
+			"id_field":           "id",
+			"debug":              "false",
+			"response":           `{"id":"created"}`,
+			"response_map.%":     "1",
+			"response_map.id":    "created",
+			"response_headers.%": "0",
+			"output_values.%":    "0",
+		},
+	}
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"url":    "http://localhost/created",
+		"method": "POST",
+	})
+
+	diff, err := resourceREST().Diff(state, config, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("expected no diff for an upgraded state, got: %#v", diff.Attributes)
+	}
+
+	// the provider settings win again
+	d := resourceREST().Data(state)
+	settings, err := expandTLSSettings(d, tlsSettings{insecure: true})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !settings.insecure {
+		t.Fatalf("expected the provider insecure setting to win over an upgraded state")
+	}
+	if _, ok := d.GetOk("timeout"); ok {
+		t.Fatalf("expected the provider timeout to win over an upgraded state")
+	}
+}
+
+func TestResourceUpgradedState_explicit(t *testing.T) {
+	raw := map[string]interface{}{
+		"timeout":  float64(60),
+		"retries":  float64(3),
+		"insecure": true,
+	}
+
+	upgraded, err := restyStateUpgradeV0(raw, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if upgraded["timeout"] != float64(60) || upgraded["retries"] != float64(3) || upgraded["insecure"] != true {
+		t.Fatalf("expected explicit settings to be kept, got: %v", upgraded)
+	}
+}
+
+const testResourceConfigInsecure = `
+provider "resty" {
+  insecure = true
+}
+
+resource "resty" "test" {
+  url      = "%s/test"
+  insecure = %t
+}
+`
+
+func TestResourceGet_insecureOverride(t *testing.T) {
+	mock := initMockHttpsServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(testResourceConfigInsecure, mock.server.URL, false),
+				ExpectError: regexp.MustCompile("certificate"),
+			},
+			{
+				Config: fmt.Sprintf(testResourceConfigInsecure, mock.server.URL, true),
+				Check:  resource.TestCheckResourceAttr("resty.test", "response", "{}"),
+			},
+		},
+	})
+}
+
+const testResourceConfigRetry = `
+resource "resty" "test" {
+  url            = "%s/flaky"
+  method         = "POST"
+  data           = "{\"name\": \"retry\"}"
+  retries        = 2
+  retry_min_wait = 0
+}
+`
+
+func TestResourcePost_retry(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigRetry, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "response", `{"name":"retry"}`),
+			},
+		},
+	})
+}
+
+const testResourceConfigPoll = `
+resource "resty" "test" {
+  url                   = "%s/async"
+  method                = "POST"
+  expected_status_codes = ["202"]
+
+  poll {
+    status_key     = "status"
+    success_values = ["READY"]
+    failure_values = ["FAILED"]
+    interval       = 0
+  }
+}
+`
+
+func TestResourcePoll(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigPoll, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "id", "async"),
+			},
+		},
+	})
+}
+
+const testResourceConfigPollFailed = `
+resource "resty" "test" {
+  url                   = "%s/async"
+  method                = "POST"
+  expected_status_codes = ["202"]
+
+  poll {
+    url            = "%s/async/failed"
+    status_key     = "status"
+    success_values = ["READY"]
+    failure_values = ["FAILED"]
+    interval       = 0
+  }
+}
+`
+
+func TestResourcePoll_failed(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(testResourceConfigPollFailed, mock.server.URL, mock.server.URL),
+				ExpectError: regexp.MustCompile("Remote operation failed with status: FAILED"),
+			},
+		},
+	})
+}
+
+const testResourceConfigMultiFilter = `
+resource "resty" "test" {
+  url      = "%s/inventory"
+  key      = "items"
+  id_field = "name"
+
+  filter {
+    name     = "meta/size"
+    operator = "gt"
+    value    = "4"
+  }
+  filter {
+    name  = "enabled"
+    value = "false"
+  }
+}
+`
+
+func TestResourceGet_withMultipleFilters(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigMultiFilter, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "id", "db"),
+			},
+		},
+	})
+}
+
+const testResourceConfigOrFilter = `
+resource "resty" "test" {
+  url         = "%s/inventory"
+  key         = "items"
+  id_field    = "name"
+  filter_mode = "or"
+
+  filter {
+    name     = "name"
+    operator = "regex"
+    value    = "^w"
+  }
+  filter {
+    name  = "name"
+    value = "cache"
+  }
+}
+`
+
+func TestResourceGet_withOrFilters(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigOrFilter, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "id", "web"),
+			},
+		},
+	})
+}
+
+const testResourceConfigNoFilterMatch = `
+resource "resty" "test" {
+  url = "%s/inventory"
+  key = "items"
+
+  filter {
+    name     = "meta/size"
+    operator = "lt"
+    value    = "1"
+  }
+}
+`
+
+func TestResourceGet_withNoFilterMatch(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(testResourceConfigNoFilterMatch, mock.server.URL),
+				ExpectError: regexp.MustCompile(`Candidates: {meta/size=2}, {meta/size=8}`),
+			},
+		},
+	})
+}
+
+const testResourceConfigOutputs = `
+resource "resty" "test" {
+  url     = "%s/objects"
+  method  = "POST"
+  data    = "{\"name\": \"outputs\", \"config\": {\"size\": 3, \"tags\": [\"a\"]}}"
+  outputs = {
+    name = "name"
+    size = "config/size"
+  }
+}
+`
+
+func TestResourceOutputs(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigOutputs, mock.server.URL),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("resty.test", "response_map.name", "outputs"),
+					resource.TestCheckResourceAttr("resty.test", "response_map.config.size", "3"),
+					resource.TestCheckResourceAttr("resty.test", "response_map.config.tags.0", "a"),
+					resource.TestCheckResourceAttr("resty.test", "output_values.name", "outputs"),
+					resource.TestCheckResourceAttr("resty.test", "output_values.size", "3"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceGet_hashID(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfig, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "id", "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"),
+			},
+		},
+	})
+}
+
+const testResourceConfigHeaderID = `
+resource "resty" "test" {
+  url         = "%s/objects"
+  method      = "POST"
+  id_field    = "missing"
+  id_strategy = "header"
+}
+`
+
+func TestResourceHeaderID(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigHeaderID, mock.server.URL),
+				Check:  resource.TestCheckResourceAttr("resty.test", "id", "1"),
+			},
+		},
+	})
+}
+
+const testResourceConfigMissingID = `
+resource "resty" "test" {
+  url                = "%s/objects"
+  method             = "POST"
+  id_field           = "missing"
+  fail_on_missing_id = true
+}
+`
+
+func TestResourceMissingID(t *testing.T) {
+	mock := initMockCrudServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(testResourceConfigMissingID, mock.server.URL),
+				ExpectError: regexp.MustCompile("does not have key 'missing'"),
+			},
+		},
+	})
+}
+
+const testResourceConfigOAuth2 = `
+provider "resty" {
+  base_url = "%s"
+
+  oauth2 {
+    token_url     = "/token"
+    client_id     = "dead"
+    client_secret = "beef"
+    audience      = "resty"
+  }
+}
+
+resource "resty" "first" {
+  url = "/bearer"
+}
+
+resource "resty" "second" {
+  url = "/bearer"
+}
+`
+
+func TestResourceGet_withOAuth2(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigOAuth2, mock.server.URL),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("resty.first", "response", `{"tokens":1}`),
+					resource.TestCheckResourceAttr("resty.second", "response", `{"tokens":1}`),
+				),
+			},
+		},
+	})
+}
+
+const testResourceConfigClientCert = `
+resource "resty" "test" {
+  url         = "%s/test"
+  server_name = "example.com"
+  ca_cert     = <<EOF
+%sEOF
+%s
+}
+`
+
+func TestResourceGet_withClientCertificate(t *testing.T) {
+	client_cert, client_key := testClientCertificate(t)
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM([]byte(client_cert))
+
+	server := httptest.NewUnstartedServer(mockHttpHandler())
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}
+	server.StartTLS()
+
+	defer server.Close()
+
+	ca_cert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	// pass the key as file to cover both ways of configuring it
+	key_file, err := ioutil.TempFile("", "resty")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(key_file.Name())
+	key_file.WriteString(client_key)
+	key_file.Close()
+
+	with_cert := fmt.Sprintf("  client_cert = <<EOF\n%sEOF\n  client_key  = %q", client_cert, key_file.Name())
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(testResourceConfigClientCert, server.URL, ca_cert, ""),
+				ExpectError: regexp.MustCompile("tls"),
+			},
+			{
+				Config: fmt.Sprintf(testResourceConfigClientCert, server.URL, ca_cert, with_cert),
+				Check:  resource.TestCheckResourceAttr("resty.test", "response", "{}"),
+			},
+		},
+	})
+}
+
+// Self signed, PEM encoded client certificate and key
+func testClientCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "resty"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+}
+
+const testResourceConfigBodyFormat = `
+resource "resty" "form" {
+  url         = "%[1]s/echo"
+  method      = "POST"
+  body_format = "form"
+  form = {
+    name = "a b"
+    size = "3"
+  }
+}
+
+resource "resty" "multipart" {
+  url         = "%[1]s/echo"
+  method      = "POST"
+  body_format = "multipart"
+  form = {
+    name = "upload"
+  }
+  multipart {
+    name = "document"
+    file = %[2]q
+  }
+  multipart {
+    name         = "note"
+    value        = "hello"
+    filename     = "note.txt"
+    content_type = "text/plain"
+  }
+}
+
+resource "resty" "xml" {
+  url         = "%[1]s/echo"
+  method      = "POST"
+  body_format = "xml"
+  data        = "<a>b</a>"
+}
+
+resource "resty" "override" {
+  url    = "%[1]s/echo"
+  method = "POST"
+  data   = "{}"
+  headers = {
+    Content-Type = "application/vnd.resty+json"
+  }
+}
+`
+
+func TestResourcePost_bodyFormat(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	file, err := ioutil.TempFile("", "resty")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("content")
+	file.Close()
+
+	filename := filepath.Base(file.Name())
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testResourceConfigBodyFormat, mock.server.URL, file.Name()),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("resty.form", "response_map.content_type", "application/x-www-form-urlencoded"),
+					resource.TestCheckResourceAttr("resty.form", "response_map.fields.name.0", "a b"),
+					resource.TestCheckResourceAttr("resty.form", "response_map.fields.size.0", "3"),
+					resource.TestMatchResourceAttr("resty.multipart", "response_map.content_type", regexp.MustCompile("^multipart/form-data; boundary=")),
+					resource.TestCheckResourceAttr("resty.multipart", "response_map.fields.name.0", "upload"),
+					resource.TestCheckResourceAttr("resty.multipart", "response_map.files.document", filename+":application/octet-stream:content"),
+					resource.TestCheckResourceAttr("resty.multipart", "response_map.files.note", "note.txt:text/plain:hello"),
+					resource.TestCheckResourceAttr("resty.xml", "response_map.content_type", "application/xml"),
+					resource.TestCheckResourceAttr("resty.xml", "response_map.body", "<a>b</a>"),
+					resource.TestCheckResourceAttr("resty.override", "response_map.content_type", "application/vnd.resty+json"),
+				),
+			},
+		},
+	})
+}
+
+const testResourceConfigBody = `
+resource "resty" "test" {
+  url    = "%s/echo"
+  method = "POST"
+  body   = <<EOF
+%s
+EOF
+  sensitive_body = jsonencode({
+    auth = { password = "beef" }
+  })
+}
+`
+
+func TestResourcePost_withBody(t *testing.T) {
+	mock := initMockHttpServer()
+
+	defer mock.server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(testResourceConfigBody, mock.server.URL, `["a"]`),
+				ExpectError: regexp.MustCompile("must be a JSON object"),
+			},
+			{
+				Config: fmt.Sprintf(testResourceConfigBody, mock.server.URL, `{"name": "a", "auth": {"user": "dead"}}`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("resty.test", "body", `{"auth":{"user":"dead"},"name":"a"}`),
+					resource.TestCheckResourceAttr("resty.test", "response_map.content_type", "application/json"),
+					resource.TestCheckResourceAttr("resty.test", "response_map.body", `{"auth":{"password":"beef","user":"dead"},"name":"a"}`),
+				),
+			},
+			{
+				// only the key order differs
+				Config:   fmt.Sprintf(testResourceConfigBody, mock.server.URL, `{"auth": {"user": "dead"}, "name": "a"}`),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func initMockHttpServer() *testHttpMock {
+	return &testHttpMock{
+		server: httptest.NewServer(mockHttpHandler()),
+	}
+}
+
+func initMockHttpsServer() *testHttpMock {
+	return &testHttpMock{
+		server: httptest.NewTLSServer(mockHttpHandler()),
+	}
+}
+
+func mockHttpHandler() http.Handler {
+	flaky := 0
+	polled := 0
+	tokens := 0
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-Is-Teapot", "Yes")
+		if r.URL.Path == "/test" {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("{}"))
+		} else if r.URL.Path == "/created" {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("{\"id\": \"created\"}"))
+		} else if r.URL.Path == "/headers" {
+			if r.Header.Get("Authorization") == "ZGVhZDpiZWVmCg==" {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("{}"))
+			} else {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		} else if r.URL.Path == "/filter" {
+			if r.Header.Get("Authorization") == "ZGVhZDpiZWVmCg==" {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("{\"content\": [{\"interesting\": \"no\", \"you\": \"failed\"},{\"interesting\": \"value\", \"working\": \"yes\"}]}"))
+			} else {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		} else if r.URL.Path == "/flaky" {
+			// fail twice before echoing the request body
+			if flaky += 1; flaky < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			} else {
+				w.WriteHeader(http.StatusOK)
+				io.Copy(w, r.Body)
+			}
+		} else if r.URL.Path == "/async" {
+			w.Header().Set("Location", "/async/status")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("{\"id\": \"async\"}"))
+		} else if r.URL.Path == "/async/status" {
+			// pending on the first poll, ready afterwards
+			w.WriteHeader(http.StatusOK)
+			if polled += 1; polled < 2 {
+				w.Write([]byte("{\"status\": \"PENDING\"}"))
+			} else {
+				w.Write([]byte("{\"status\": \"READY\"}"))
+			}
+		} else if r.URL.Path == "/async/failed" {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("{\"status\": \"FAILED\"}"))
+		} else if r.URL.Path == "/inventory" {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"items": [{"name": "web", "meta": {"size": 2}, "enabled": true}, {"name": "db", "meta": {"size": 8}, "enabled": false}]}`))
+		} else if strings.HasPrefix(r.URL.Path, "/paged/") {
+			// five items served two at a time
+			items := []string{`{"name": "i1"}`, `{"name": "i2"}`, `{"name": "i3"}`, `{"name": "i4"}`, `{"name": "i5"}`}
+			start := 0
+			if r.URL.Path == "/paged/offset" {
+				fmt.Sscanf(r.URL.Query().Get("offset"), "%d", &start)
+			} else if r.URL.Path == "/paged/page" {
+				// pages are counted from 0
+				fmt.Sscanf(r.URL.Query().Get("p"), "%d", &start)
+				start *= 2
+			} else {
+				fmt.Sscanf(r.URL.Query().Get("cursor"), "%d", &start)
+			}
+			end := start + 2
+			if end > len(items) {
+				end = len(items)
+			}
+			next := ""
+			if end < len(items) {
+				if r.URL.Path == "/paged/link" {
+					w.Header().Set("Link", fmt.Sprintf(`</paged/link?cursor=%d>; rel="next", </paged/link>; rel="first"`, end))
+				}
+				next = fmt.Sprintf("%d", end)
+			}
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{"data": {"items": [%s]}, "next": "%s"}`, strings.Join(items[start:end], ","), next)
+		} else if r.URL.Path == "/token" {
+			user, pass, _ := r.BasicAuth()
+			if r.FormValue("grant_type") != "client_credentials" || r.FormValue("audience") != "resty" || user != "dead" || pass != "beef" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			tokens += 1
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"access_token": "ZGVhZDpiZWVmCg==", "token_type": "bearer", "expires_in": 3600}`))
+		} else if r.URL.Path == "/bearer" {
+			if r.Header.Get("Authorization") == "Bearer ZGVhZDpiZWVmCg==" {
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprintf(w, `{"tokens": %d}`, tokens)
+			} else {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		} else if r.URL.Path == "/echo" {
+			// describe how the request body was encoded
+			echo := map[string]interface{}{"content_type": r.Header.Get("Content-Type")}
+			if strings.HasPrefix(echo["content_type"].(string), "multipart/") {
+				if err := r.ParseMultipartForm(1 << 20); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				files := map[string]string{}
+				for name, headers := range r.MultipartForm.File {
+					f, _ := headers[0].Open()
+					content, _ := ioutil.ReadAll(f)
+					f.Close()
+					files[name] = headers[0].Filename + ":" + headers[0].Header.Get("Content-Type") + ":" + string(content)
+				}
+				echo["fields"] = r.MultipartForm.Value
+				echo["files"] = files
+			} else if echo["content_type"] == "application/x-www-form-urlencoded" {
+				r.ParseForm()
+				echo["fields"] = r.PostForm
+			} else {
+				body, _ := ioutil.ReadAll(r.Body)
+				echo["body"] = string(body)
+			}
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(echo)
+		} else if r.URL.Path == "/query" {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(map[string]string{"query": r.URL.RawQuery})
+		} else if r.URL.Path == "/basic" {
+			if user, pass, ok := r.BasicAuth(); ok && user == "dead" && pass == "beef" {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("{}"))
+			} else {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func (m *testCrudMock) count() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return len(m.objects)
+}
+
+func initMockCrudServer() *testCrudMock {
+	mock := &testCrudMock{
+		objects: make(map[string]map[string]interface{}),
+	}
+
+	mock.server = httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mock.lock.Lock()
+			defer mock.lock.Unlock()
+
+			w.Header().Set("Content-Type", "application/json")
+			mock.queries = append(mock.queries, r.URL.RawQuery)
+
+			id := strings.TrimPrefix(r.URL.Path, "/objects/")
+			if r.URL.Path == "/objects" && r.Method == "POST" {
+				object := make(map[string]interface{})
+				json.NewDecoder(r.Body).Decode(&object)
+				mock.next += 1
+				object["id"] = fmt.Sprintf("%d", mock.next)
+				mock.objects[object["id"].(string)] = object
+				w.Header().Set("Location", "/objects/"+object["id"].(string))
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(object)
+			} else if object, ok := mock.objects[id]; ok && strings.HasPrefix(r.URL.Path, "/objects/") {
+				if r.Method == "DELETE" {
+					delete(mock.objects, id)
+					w.WriteHeader(http.StatusNoContent)
+				} else if r.Method == "PUT" || r.Method == "PATCH" {
+					json.NewDecoder(r.Body).Decode(&object)
+					object["id"] = id
+					w.WriteHeader(http.StatusOK)
+					json.NewEncoder(w).Encode(object)
+				} else {
+					w.WriteHeader(http.StatusOK)
+					json.NewEncoder(w).Encode(object)
+				}
+			} else {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+
+	return mock
 }
@@ -0,0 +1,179 @@
+package resty
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+// Payload inputs which hash_secrets keeps out of the state, only their
+// SHA-256 digest is stored. multipart only has the value of its parts hashed.
+var payloadKeys = []string{"data", "sensitive_body", "form", "multipart"}
+
+// Inputs which carry the resource credentials, hashed as well
+var credentialKeys = []string{"password", "headers", "client_key"}
+
+var secretKeys = append(append([]string{"update_data"}, payloadKeys...), credentialKeys...)
+
+// Options which make the resource send requests after it was created
+var followUpKeys = []string{"read_method", "read_path", "exists_method", "destroy_method", "destroy_path", "destroy_url"}
+
+// A digest in the state stands for the cleartext in the configuration
+func suppressHashedSecret(k, old, new string, d *schema.ResourceData) bool {
+	if hashed, _ := d.Get("hash_secrets").(bool); !hashed || old == "" {
+		return false
+	}
+	return old == hashString(new)
+}
+
+// Let the digests in the state stand for the cleartext of every secret
+func suppressHashedSecrets(s map[string]*schema.Schema) {
+	for _, key := range secretKeys {
+		if key == "multipart" {
+			s[key].Elem.(*schema.Resource).Schema["value"].DiffSuppressFunc = suppressHashedSecret
+		} else {
+			s[key].DiffSuppressFunc = suppressHashedSecret
+		}
+	}
+}
+
+// With hash_secrets the state only holds digests, so the cleartext of a
+// secret is only known while it is being changed
+func secretAvailable(d *schema.ResourceData, key string) bool {
+	hashed, _ := d.GetChange("hash_secrets")
+	return hashed != true || d.HasChange(key)
+}
+
+// Fail rather than send the digest of a secret in place of its cleartext
+func requireSecrets(d *schema.ResourceData, keys ...string) error {
+	for _, key := range keys {
+		if secretSet(d.Get(key)) && !secretAvailable(d, key) {
+			return fmt.Errorf("%s is only stored as a digest because of hash_secrets and can't be sent again", key)
+		}
+	}
+	return nil
+}
+
+// Whether a secret holds anything, be it the cleartext or its digest
+func secretSet(value interface{}) bool {
+	switch value := value.(type) {
+	case string:
+		return value != ""
+	case map[string]interface{}:
+		return len(value) > 0
+	case []interface{}:
+		return len(value) > 0
+	}
+	return false
+}
+
+// Replace the secrets in the state by their digest
+func hashSecrets(d *schema.ResourceData) error {
+	if _, ok := d.GetOk("hash_secrets"); !ok {
+		return nil
+	}
+
+	for _, key := range secretKeys {
+		if !secretAvailable(d, key) {
+			continue
+		}
+
+		var err error
+		switch value := d.Get(key).(type) {
+		case string:
+			if value == "" {
+				continue
+			}
+			// the digest must match the diff, which holds the normalized body
+			if key == "sensitive_body" {
+				value = normalizeBody(value)
+			}
+			err = d.Set(key, hashString(value))
+		case map[string]interface{}:
+			hashed := make(map[string]interface{})
+			for k, v := range value {
+				hashed[k] = hashString(v.(string))
+			}
+			err = d.Set(key, hashed)
+		case []interface{}:
+			hashed := make([]interface{}, 0, len(value))
+			for _, p := range value {
+				part := make(map[string]interface{})
+				for k, v := range p.(map[string]interface{}) {
+					part[k] = v
+				}
+				if v := part["value"].(string); v != "" {
+					part["value"] = hashString(v)
+				}
+				hashed = append(hashed, part)
+			}
+			err = d.Set(key, hashed)
+		}
+		if err != nil {
+			return fmt.Errorf("Error hashing %s: %s", key, err)
+		}
+	}
+
+	return nil
+}
+
+// Only the digest of the credentials is left after create, so refreshing or
+// destroying the object could not authenticate. Ask for the credentials to
+// be moved to the provider instead.
+func hashedCredentialsDiff(d *schema.ResourceDiff) error {
+	if !d.Get("hash_secrets").(bool) {
+		return nil
+	}
+
+	for _, credential := range credentialKeys {
+		if !secretSet(d.Get(credential)) {
+			continue
+		}
+		for _, key := range followUpKeys {
+			if d.Get(key).(string) != "" {
+				return fmt.Errorf("%s is only stored as a digest because of hash_secrets and can't be sent along %s, set it on the provider instead", credential, key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// An in-place update which sends the request again needs the payload and the
+// credentials, which fails once only their digest is left. Ask for them to be
+// changed along instead. Updates sending no request don't need them.
+func hashedSecretsDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if err := hashedCredentialsDiff(d); err != nil {
+		return err
+	}
+
+	hashed, _ := d.GetChange("hash_secrets")
+	if d.Id() == "" || hashed != true {
+		return nil
+	}
+
+	// drift detection may have asked for an update too
+	resend := !d.NewValueKnown("response")
+	for _, key := range requestKeys {
+		// HasChange would compare against the cleartext configuration
+		resend = resend || len(d.GetChangedKeysPrefix(key)) > 0
+	}
+	if !resend {
+		return nil
+	}
+
+	keys := payloadKeys
+	// update_data replaces the payload on updates
+	if d.Get("update_data").(string) != "" {
+		keys = []string{"update_data"}
+	}
+
+	for _, key := range append(keys, credentialKeys...) {
+		old, _ := d.GetChange(key)
+		if secretSet(old) && len(d.GetChangedKeysPrefix(key)) == 0 {
+			return fmt.Errorf("%s is only stored as a digest because of hash_secrets, change it as well to update the resource in place", key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package resty
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Everything that ends up in the tls.Config of a transport
+type tlsSettings struct {
+	insecure        bool
+	ca_cert         string
+	client_cert     string
+	client_key      string
+	server_name     string
+	min_tls_version string
+}
+
+// TLS attributes shared by the provider, the resource and the data sources
+func tlsSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"ca_cert": {
+			Type:        schema.TypeString,
+			Description: "PEM encoded CA certificates used to validate the server",
+			Optional:    true,
+		},
+		"ca_file": {
+			Type:        schema.TypeString,
+			Description: "Path to a PEM encoded CA bundle used to validate the server",
+			Optional:    true,
+		},
+		"client_cert": {
+			Type:        schema.TypeString,
+			Description: "PEM encoded client certificate, or the path to one",
+			Optional:    true,
+		},
+		"client_key": {
+			Type:        schema.TypeString,
+			Description: "PEM encoded client key, or the path to one",
+			Optional:    true,
+			Sensitive:   true,
+		},
+		"server_name": {
+			Type:        schema.TypeString,
+			Description: "Server name used for SNI and certificate validation",
+			Optional:    true,
+		},
+		"min_tls_version": {
+			Type:         schema.TypeString,
+			Description:  "Minimum TLS version, one of 1.0, 1.1, 1.2 or 1.3",
+			Optional:     true,
+			ValidateFunc: validation.StringInSlice([]string{"1.0", "1.1", "1.2", "1.3"}, false),
+		},
+	}
+}
+
+// Override the settings in base with whatever is set in d
+func expandTLSSettings(d *schema.ResourceData, base tlsSettings) (tlsSettings, error) {
+	settings := base
+
+	if v, ok := d.GetOkExists("insecure"); ok {
+		settings.insecure = v.(bool)
+	}
+	if v := d.Get("server_name").(string); v != "" {
+		settings.server_name = v
+	}
+	if v := d.Get("min_tls_version").(string); v != "" {
+		settings.min_tls_version = v
+	}
+
+	ca_cert := d.Get("ca_cert").(string)
+	if ca_file := d.Get("ca_file").(string); ca_file != "" {
+		pem, err := ioutil.ReadFile(ca_file)
+		if err != nil {
+			return settings, fmt.Errorf("Error reading ca_file: %s", err)
+		}
+		ca_cert += "\n" + string(pem)
+	}
+	if ca_cert != "" {
+		settings.ca_cert = ca_cert
+	}
+
+	for _, field := range []struct {
+		key    string
+		target *string
+	}{
+		{"client_cert", &settings.client_cert},
+		{"client_key", &settings.client_key},
+	} {
+		if v := d.Get(field.key).(string); v != "" {
+			pem, err := readPEM(v)
+			if err != nil {
+				return settings, fmt.Errorf("Error reading %s: %s", field.key, err)
+			}
+			*field.target = pem
+		}
+	}
+
+	return settings, nil
+}
+
+// Accept either PEM content or the path of a file holding it
+func readPEM(value string) (string, error) {
+	if strings.Contains(value, "-----BEGIN") {
+		return value, nil
+	}
+	pem, err := ioutil.ReadFile(value)
+	return string(pem), err
+}
+
+// Unique key of the settings, used to share transports
+func (s tlsSettings) key() string {
+	return fmt.Sprintf("insecure=%t,server_name=%s,min_tls_version=%s,certs=%s",
+		s.insecure, s.server_name, s.min_tls_version, hashString(s.ca_cert+s.client_cert+s.client_key))
+}
+
+func (s tlsSettings) config() (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: s.insecure,
+		ServerName:         s.server_name,
+		MinVersion:         tlsVersions[s.min_tls_version],
+	}
+
+	if s.ca_cert != "" {
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM([]byte(s.ca_cert)) {
+			return nil, fmt.Errorf("No certificates found in ca_cert or ca_file")
+		}
+	}
+
+	if s.client_cert != "" || s.client_key != "" {
+		certificate, err := tls.X509KeyPair([]byte(s.client_cert), []byte(s.client_key))
+		if err != nil {
+			return nil, fmt.Errorf("Error loading client certificate: %s", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
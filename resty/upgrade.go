@@ -0,0 +1,127 @@
+package resty
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+// Defaults of the resource settings which now fall back to the provider
+var resourceRESTV0Defaults = map[string]interface{}{
+	"timeout":  10,
+	"retries":  1,
+	"insecure": false,
+}
+
+// The resource as it was before the state was versioned, only needed to
+// read states still in the legacy flatmap format
+func resourceRESTV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"url": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"method": {
+				Type:     schema.TypeString,
+				Default:  "GET",
+				Optional: true,
+			},
+			"headers": {
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+			"data": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"insecure": {
+				Type:     schema.TypeBool,
+				Default:  false,
+				Optional: true,
+			},
+			"force_new": {
+				Type:      schema.TypeList,
+				Elem:      &schema.Schema{Type: schema.TypeString},
+				Optional:  true,
+				Sensitive: true,
+				ForceNew:  true,
+			},
+			"id_field": {
+				Type:     schema.TypeString,
+				Default:  "id",
+				Optional: true,
+			},
+			"timeout": {
+				Type:     schema.TypeInt,
+				Default:  10,
+				Optional: true,
+			},
+			"retries": {
+				Type:     schema.TypeInt,
+				Default:  1,
+				Optional: true,
+			},
+			"username": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"password": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"debug": {
+				Type:     schema.TypeBool,
+				Default:  false,
+				Optional: true,
+			},
+			"filter": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"value": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+					},
+				},
+			},
+			"key": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"response": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"response_headers": {
+				Type:     schema.TypeMap,
+				Computed: true,
+			},
+		},
+	}
+}
+
+// Version 0 stored the defaults of timeout, retries and insecure, which would
+// otherwise keep winning over the provider settings. There is no telling them
+// apart from the same values set explicitly, those show up as a change.
+func restyStateUpgradeV0(rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+	for key, value := range resourceRESTV0Defaults {
+		if v, ok := rawState[key]; ok && fmt.Sprint(v) == fmt.Sprint(value) {
+			log.Printf("[RESTY] Dropping the former default of %s from the state", key)
+			delete(rawState, key)
+		}
+	}
+	return rawState, nil
+}